@@ -0,0 +1,258 @@
+package board
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the name of the entry hash, hashed into it
+const entryDomain = "crypto-voting/board-entry"
+
+// Kind tells what an entry holds
+type Kind string
+
+// the kinds of entries posted during an election
+const (
+	KindManifest          Kind = "manifest"           // the manifest of the election
+	KindDeal              Kind = "deal"               // the deals of a trustee in the key ceremony
+	KindResponse          Kind = "response"           // the responses of a trustee in the key ceremony
+	KindJustification     Kind = "justification"      // the justifications of a trustee in the key ceremony
+	KindCeremonyResult    Kind = "ceremony-result"    // the public key and commitments a trustee ended the key ceremony with
+	KindKey               Kind = "key"                // the public key and commitments the key ceremony ended with
+	KindRegistry          Kind = "registry"           // the voters of the election, with the keys they sign their ballots with
+	KindBallot            Kind = "ballot"             // a cast ballot
+	KindSpoiledBallot     Kind = "spoiled-ballot"     // a ballot that was audited instead of cast, with its randomness
+	KindBallotRoot        Kind = "ballot-root"        // the root of the tree of every cast ballot, once voting is closed
+	KindMixRecord         Kind = "mix-record"         // the record of a mix server
+	KindPartialDecryption Kind = "partial-decryption" // the shadows a trustee extracted, with their proofs
+	KindResult            Kind = "result"             // the outcome of the election
+)
+
+// ErrBrokenChain is returned when the entries of a board don't link up, or one of them was altered
+var ErrBrokenChain = errors.New("broken bulletin board chain")
+
+// ErrInvalidSignature is returned for an entry whose signature doesn't hold
+var ErrInvalidSignature = errors.New("invalid entry signature")
+
+// ErrOutOfRange is returned when reading entries the board doesn't hold
+var ErrOutOfRange = errors.New("entries out of range")
+
+// Entry is a single post on the board
+type Entry struct {
+	Index     int             // the position of the entry, starting at 0
+	Kind      Kind            // what the entry holds
+	Time      time.Time       // when the entry was appended
+	Author    []byte          // the public key of the author, who signed the entry
+	Payload   json.RawMessage // the artifact posted, in its JSON form
+	Previous  []byte          // the hash of the previous entry, empty for the first one
+	Hash      []byte          // the hash of everything above
+	Signature []byte          // the author's Schnorr signature over the hash
+}
+
+// hash computes the hash of the entry, over everything but the hash and the signature
+// every field is prefixed by its length, so fields can't run into each other
+func (e *Entry) hash() []byte {
+	hash := sha256.New()
+	length := make([]byte, 8)
+	for _, part := range [][]byte{
+		[]byte(entryDomain),
+		binary.BigEndian.AppendUint64(nil, uint64(e.Index)),
+		[]byte(e.Kind),
+		binary.BigEndian.AppendUint64(nil, uint64(e.Time.UnixNano())),
+		e.Author,
+		e.Payload,
+		e.Previous,
+	} {
+		binary.BigEndian.PutUint64(length, uint64(len(part)))
+		hash.Write(length)
+		hash.Write(part)
+	}
+	return hash.Sum(nil)
+}
+
+// Board is a bulletin board backed by a file
+// a board is safe to use from several goroutines, but only one process should have its file open
+type Board struct {
+	suite suites.Suite // the suite of the authors' keys
+
+	mu      sync.Mutex
+	file    *os.File // the file of the board, opened for appending
+	entries []*Entry // every entry of the board, in order
+}
+
+// Open opens the board kept in the file at path, creating the file if it doesn't exist
+// the entries already on the board are read and their chain is checked, so a tampered board is never appended to
+func Open(suite suites.Suite, path string) (*Board, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readEntries(file)
+	if err == nil {
+		err = VerifyChain(suite, entries)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Board{suite: suite, file: file, entries: entries}, nil
+}
+
+// ReadEntries reads every entry of the board kept in the file at path, without checking them
+// this is for auditors, who check the entries with VerifyChain
+func ReadEntries(path string) ([]*Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readEntries(file)
+}
+
+// helper function, reads the entries from a board file, one per line
+func readEntries(r io.Reader) ([]*Entry, error) {
+	var entries []*Entry
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return entries, nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		entry := &Entry{}
+		if err := json.Unmarshal(line, entry); err != nil {
+			// a line cut short is what a crash while appending leaves behind
+			return nil, fmt.Errorf("%w: entry %d can't be read: %v", ErrBrokenChain, len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// Append signs an artifact with the author's private key and posts it at the end of the board
+// the payload is posted in its JSON form, and the entry is on disk before Append returns
+func (b *Board) Append(author kyber.Scalar, kind Kind, payload interface{}) (*Entry, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	// the JSON form written to the file must be the one that was hashed
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, err
+	}
+	authorKey, err := b.suite.Point().Mul(author, nil).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		return nil, os.ErrClosed
+	}
+	entry := &Entry{
+		Index:   len(b.entries),
+		Kind:    kind,
+		Time:    time.Now().UTC(),
+		Author:  authorKey,
+		Payload: compact.Bytes(),
+	}
+	if len(b.entries) > 0 {
+		entry.Previous = b.entries[len(b.entries)-1].Hash
+	}
+	entry.Hash = entry.hash()
+	if entry.Signature, err = schnorr.Sign(b.suite, author, entry.Hash); err != nil {
+		return nil, err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.file.Write(append(line, '\n')); err != nil {
+		return nil, err
+	}
+	if err := b.file.Sync(); err != nil {
+		return nil, err
+	}
+	b.entries = append(b.entries, entry)
+	return entry, nil
+}
+
+// Len returns the number of entries on the board
+func (b *Board) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Read returns the entries from index from (included) to index to (excluded)
+func (b *Board) Read(from, to int) ([]*Entry, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if from < 0 || to > len(b.entries) || from > to {
+		return nil, fmt.Errorf("%w: [%d, %d) of %d entries", ErrOutOfRange, from, to, len(b.entries))
+	}
+	return append([]*Entry(nil), b.entries[from:to]...), nil
+}
+
+// Verify checks the chain of every entry on the board
+func (b *Board) Verify() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return VerifyChain(b.suite, b.entries)
+}
+
+// Close closes the file of the board
+func (b *Board) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.file == nil {
+		return nil
+	}
+	err := b.file.Close()
+	b.file = nil
+	return err
+}
+
+// VerifyChain checks a list of entries, from the first entry of a board:
+// every entry is in its place, holds the hash of the entry before it, hashes to its own hash,
+// and is signed by its author
+func VerifyChain(suite suites.Suite, entries []*Entry) error {
+	var previous []byte
+	for i, entry := range entries {
+		if entry.Index != i {
+			return fmt.Errorf("%w: entry %d claims index %d", ErrBrokenChain, i, entry.Index)
+		}
+		if !bytes.Equal(entry.Previous, previous) {
+			return fmt.Errorf("%w: entry %d doesn't follow entry %d", ErrBrokenChain, i, i-1)
+		}
+		if !bytes.Equal(entry.hash(), entry.Hash) {
+			return fmt.Errorf("%w: entry %d was altered", ErrBrokenChain, i)
+		}
+		author := suite.Point()
+		if err := author.UnmarshalBinary(entry.Author); err != nil {
+			return fmt.Errorf("%w: entry %d: author: %v", ErrInvalidSignature, i, err)
+		}
+		if err := schnorr.Verify(suite, author, entry.Hash, entry.Signature); err != nil {
+			return fmt.Errorf("%w: entry %d: %v", ErrInvalidSignature, i, err)
+		}
+		previous = entry.Hash
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+// Package board is an append-only bulletin board for the artifacts of an election.
+//
+// Every entry is signed by its author and holds the hash of the entry before it,
+// so the board forms a chain: an entry can't be changed, removed or reordered
+// without breaking every hash after it.
+// The board is kept in a local file, one JSON entry per line, and checked from start to end when it is opened.
+package board
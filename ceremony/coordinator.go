@@ -0,0 +1,235 @@
+package ceremony
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/suites"
+
+	"github.com/SpencerBouck/crypto-voting/board"
+)
+
+// the header carrying a trustee's signature over the message it posts, hex encoded
+const signatureHeader = "X-Trustee-Signature"
+
+// the name of the signature over a posted message, signed along with it
+const postSignatureDomain = "crypto-voting/ceremony-post"
+
+// the phases of the ceremony, in the order they run
+// each trustee posts exactly one message per phase,
+// and a phase can only be read once every trustee has posted to it
+var phases = []string{"deals", "responses", "justifications", "results"}
+
+// the kind of bulletin board entry the messages of each phase are posted as
+var phaseKinds = map[string]board.Kind{
+	"deals":          board.KindDeal,
+	"responses":      board.KindResponse,
+	"justifications": board.KindJustification,
+	"results":        board.KindCeremonyResult,
+}
+
+// post is a message a trustee published during a phase
+type post struct {
+	From int             // the index of the trustee
+	Body json.RawMessage // the message, as sent by the trustee
+}
+
+// Coordinator is the bulletin board of a key ceremony
+// it never sees a secret: it only relays the (encrypted or signed) dkg messages between the trustees
+// Trustees register their long-term public keys, and then go through the
+// deal, response and justification phases by posting to and reading from the coordinator
+type Coordinator struct {
+	suite suites.Suite // the cipher suite of the election
+	n, t  int          // the number of trustees, and the threshold
+
+	mu         sync.Mutex
+	publicKeys [][]byte                // the long-term public keys of the registered trustees, in order of index
+	posts      map[string]map[int]post // the messages of each phase, by trustee index
+
+	board    *board.Board // the bulletin board every message is posted to, if any
+	boardKey kyber.Scalar // the key the coordinator signs its board entries with
+}
+
+// NewCoordinator creates the coordinator of a ceremony between n trustees, with threshold t
+// the trustees must use the same cipher suite as the coordinator
+func NewCoordinator(suite suites.Suite, n, t int) *Coordinator {
+	posts := make(map[string]map[int]post)
+	for _, phase := range phases {
+		posts[phase] = make(map[int]post)
+	}
+	return &Coordinator{suite: suite, n: n, t: t, posts: posts}
+}
+
+// UseBoard has the coordinator post every message of the ceremony to a bulletin board, signed with the given key,
+// so the deals, responses, justifications and results can be audited once the ceremony is over
+// must be called before the coordinator starts serving
+func (c *Coordinator) UseBoard(b *board.Board, key kyber.Scalar) {
+	c.board = b
+	c.boardKey = key
+}
+
+// ServeHTTP handles the requests of the trustees
+//
+//	POST /register           registers a long-term public key, answers with the trustee's index
+//	GET  /participants       the public keys of all trustees, once everyone registered
+//	POST /phases/{phase}?from=i  publishes the message of trustee i for a phase, signed with its registered key
+//	GET  /phases/{phase}     every message of a phase, once every trustee posted to it
+//
+// reads of incomplete steps answer 503, so the trustees know to try again later
+func (c *Coordinator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/register" && r.Method == http.MethodPost:
+		c.handleRegister(w, r)
+	case r.URL.Path == "/participants" && r.Method == http.MethodGet:
+		c.handleParticipants(w, r)
+	case strings.HasPrefix(r.URL.Path, "/phases/") && r.Method == http.MethodPost:
+		c.handlePost(w, r, strings.TrimPrefix(r.URL.Path, "/phases/"))
+	case strings.HasPrefix(r.URL.Path, "/phases/") && r.Method == http.MethodGet:
+		c.handleRead(w, r, strings.TrimPrefix(r.URL.Path, "/phases/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// registers the long-term public key of a trustee
+// trustees are indexed in the order they register
+func (c *Coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var publicKey []byte
+	if err := json.NewDecoder(r.Body).Decode(&publicKey); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := decodePoint(c.suite, publicKey); err != nil {
+		http.Error(w, "invalid public key: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.publicKeys) >= c.n {
+		http.Error(w, "all trustees are already registered", http.StatusConflict)
+		return
+	}
+	index := len(c.publicKeys)
+	c.publicKeys = append(c.publicKeys, publicKey)
+	writeJSON(w, index)
+}
+
+// lists the long-term public keys of every trustee
+func (c *Coordinator) handleParticipants(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.publicKeys) < c.n {
+		http.Error(w, fmt.Sprintf("%d of %d trustees registered", len(c.publicKeys), c.n), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, wireParticipants{Suite: c.suite.String(), Threshold: c.t, PublicKeys: c.publicKeys})
+}
+
+// publishes the message of a trustee for a phase
+// the message must be signed with the long-term key the trustee registered,
+// so no one else can post in their place
+func (c *Coordinator) handlePost(w http.ResponseWriter, r *http.Request, phase string) {
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil || from < 0 || from >= c.n {
+		http.Error(w, "invalid trustee index", http.StatusBadRequest)
+		return
+	}
+	signature, err := hex.DecodeString(r.Header.Get(signatureHeader))
+	if err != nil || len(signature) == 0 {
+		http.Error(w, "missing or malformed signature", http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !json.Valid(body) {
+		http.Error(w, "message is not JSON", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	posts, ok := c.posts[phase]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if from >= len(c.publicKeys) {
+		http.Error(w, "trustee isn't registered", http.StatusUnauthorized)
+		return
+	}
+	publicKey, err := decodePoint(c.suite, c.publicKeys[from])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := schnorr.Verify(c.suite, publicKey, postMessage(phase, from, body), signature); err != nil {
+		http.Error(w, "invalid signature: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if _, ok := posts[from]; ok {
+		http.Error(w, "trustee already posted to this phase", http.StatusConflict)
+		return
+	}
+	if c.board != nil {
+		if _, err := c.board.Append(c.boardKey, phaseKinds[phase], post{From: from, Body: json.RawMessage(body)}); err != nil {
+			http.Error(w, "posting to the bulletin board: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	posts[from] = post{From: from, Body: json.RawMessage(body)}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reads every message of a phase, in order of trustee index
+func (c *Coordinator) handleRead(w http.ResponseWriter, r *http.Request, phase string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	posts, ok := c.posts[phase]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if len(posts) < c.n {
+		http.Error(w, fmt.Sprintf("%d of %d trustees posted", len(posts), c.n), http.StatusServiceUnavailable)
+		return
+	}
+
+	all := make([]post, 0, len(posts))
+	for _, p := range posts {
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].From < all[j].From })
+	writeJSON(w, all)
+}
+
+// helper function, what a trustee signs when posting: the phase, its index and the message
+// binding the phase and index means a signed message can't be replayed into another phase or slot
+func postMessage(phase string, from int, body []byte) []byte {
+	message := []byte(postSignatureDomain + "/" + phase + "/" + strconv.Itoa(from) + "/")
+	return append(message, body...)
+}
+
+// helper function, answers a request with a JSON body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,59 @@
+package ceremony
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// RunLocal runs a whole ceremony over localhost:
+// a coordinator and n trustee nodes, each node running in its own goroutine
+// useful for testing, and for benchmarks that need the shares of every trustee
+// returns the shares, in order of trustee index
+func RunLocal(ctx context.Context, suite suites.Suite, n, t int) (shares []*dkg.DistKeyShare, err error) {
+
+	// start the coordinator on a free port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: NewCoordinator(suite, n, t)}
+	go server.Serve(listener)
+	defer server.Close()
+	coordinatorURL := "http://" + listener.Addr().String()
+
+	// the outcome of a single node
+	type outcome struct {
+		index int
+		share *dkg.DistKeyShare
+		err   error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stop the other nodes if one fails
+
+	// start the trustees
+	outcomes := make(chan outcome, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			node := NewNode(suite, coordinatorURL)
+			distShare, err := node.Run(ctx)
+			outcomes <- outcome{index: node.Index(), share: distShare, err: err}
+		}()
+	}
+
+	// collect the shares
+	shares = make([]*dkg.DistKeyShare, n)
+	for i := 0; i < n; i++ {
+		result := <-outcomes
+		if result.err != nil {
+			return nil, fmt.Errorf("trustee %d: %v", result.index, result.err)
+		}
+		shares[result.index] = result.share
+	}
+	return // shares, nil
+}
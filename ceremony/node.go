@@ -0,0 +1,346 @@
+package ceremony
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.dedis.ch/kyber/v3"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/suites"
+
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// Node is a trustee taking part in a key ceremony
+// it holds its own long-term secret, and nothing else:
+// every other trustee is only known through the coordinator
+type Node struct {
+	coordinator string       // the base URL of the coordinator
+	client      *http.Client // the client used to talk to the coordinator
+	suite       suites.Suite // the cipher suite of the election
+
+	secret kyber.Scalar // the long-term private key of the trustee
+	public kyber.Point  // the long-term public key of the trustee
+	index  int          // the index of the trustee, assigned by the coordinator
+
+	// PollInterval is how long to wait before asking again for a step that is not complete yet
+	PollInterval time.Duration
+}
+
+// NewNode creates a trustee with a fresh long-term key pair,
+// which will run the ceremony through the coordinator at the given URL
+// the key pair belongs to the given suite, which must be the suite of the coordinator
+func NewNode(suite suites.Suite, coordinatorURL string) *Node {
+	secret := suite.Scalar().Pick(suite.RandomStream())
+	return &Node{
+		coordinator:  coordinatorURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		suite:        suite,
+		secret:       secret,
+		public:       suite.Point().Mul(secret, nil),
+		index:        -1,
+		PollInterval: 100 * time.Millisecond,
+	}
+}
+
+// Index returns the index assigned to the trustee, or -1 before it registered
+func (n *Node) Index() int { return n.index }
+
+// Run takes part in the ceremony from start to finish
+// follows the same three phases as the in-process dkg of the voting package (deals, responses, justifications),
+// but every message goes through the coordinator
+// returns the trustee's share of the election key
+func (n *Node) Run(ctx context.Context) (*dkg.DistKeyShare, error) {
+
+	// register, and learn who the other trustees are
+	publicKey, err := n.public.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if err := n.send(ctx, "/register", publicKey, &n.index); err != nil {
+		return nil, fmt.Errorf("registering: %v", err)
+	}
+	var participants wireParticipants
+	if err := n.poll(ctx, "/participants", &participants); err != nil {
+		return nil, fmt.Errorf("reading participants: %v", err)
+	}
+	if participants.Suite != n.suite.String() {
+		return nil, fmt.Errorf("%w: the ceremony runs on %s, not %s", voting.ErrSuiteMismatch, participants.Suite, n.suite.String())
+	}
+	partPubs, err := decodePoints(n.suite, participants.PublicKeys)
+	if err != nil {
+		return nil, fmt.Errorf("decoding participants: %v", err)
+	}
+	// the coordinator could hand out another key under our index, to take our place in the ceremony
+	if n.index < 0 || n.index >= len(partPubs) || !partPubs[n.index].Equal(n.public) {
+		return nil, fmt.Errorf("the coordinator lists another public key for trustee %d", n.index)
+	}
+
+	// creates a key generator
+	// uses only this trustee's private key
+	generator, err := dkg.NewDistKeyGenerator(n.suite, n.secret, partPubs, participants.Threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1) Deals
+	// publish a deal for every other trustee, each encrypted to its recipient
+	deals, err := generator.Deals()
+	if err != nil {
+		return nil, err
+	}
+	outgoingDeals := make([]*wireDeal, 0, len(deals))
+	for to, deal := range deals {
+		wire, err := encodeDeal(to, deal)
+		if err != nil {
+			return nil, err
+		}
+		outgoingDeals = append(outgoingDeals, wire)
+	}
+	if err := n.publish(ctx, "deals", outgoingDeals); err != nil {
+		return nil, fmt.Errorf("publishing deals: %v", err)
+	}
+
+	// process the deals meant for this trustee
+	var dealPosts [][]*wireDeal
+	if err := n.readPhase(ctx, "deals", &dealPosts); err != nil {
+		return nil, fmt.Errorf("reading deals: %v", err)
+	}
+	responses := make([]*dkg.Response, 0, len(partPubs))
+	for _, incomingDeals := range dealPosts {
+		for _, wire := range incomingDeals {
+			if wire.To != n.index {
+				continue // meant for another trustee
+			}
+			deal, err := decodeDeal(n.suite, wire)
+			if err != nil {
+				return nil, fmt.Errorf("decoding deal of trustee %d: %v", wire.Index, err)
+			}
+			response, err := generator.ProcessDeal(deal)
+			if err != nil {
+				return nil, fmt.Errorf("processing deal of trustee %d: %v", wire.Index, err)
+			}
+			responses = append(responses, response)
+		}
+	}
+
+	// 2) Responses
+	// everyone can process every response
+	if err := n.publish(ctx, "responses", responses); err != nil {
+		return nil, fmt.Errorf("publishing responses: %v", err)
+	}
+	var responsePosts [][]*dkg.Response
+	if err := n.readPhase(ctx, "responses", &responsePosts); err != nil {
+		return nil, fmt.Errorf("reading responses: %v", err)
+	}
+	justifications := make([]*wireJustification, 0)
+	for _, incomingResponses := range responsePosts {
+		for _, response := range incomingResponses {
+
+			// don't justify to yourself
+			if response.Response.Index == uint32(n.index) {
+				continue
+			}
+
+			// handle response to the deal, justify deal to responder
+			justification, err := generator.ProcessResponse(response)
+			if err != nil {
+				return nil, fmt.Errorf("processing response of trustee %d: %v", response.Response.Index, err)
+			}
+
+			// justification will be nil if there is nothing to justify
+			// this is normally the case
+			if justification != nil {
+				wire, err := encodeJustification(justification)
+				if err != nil {
+					return nil, err
+				}
+				justifications = append(justifications, wire)
+			}
+		}
+	}
+
+	// 3) Justifications
+	// usually no justification is needed, but every trustee still posts its (empty) list to close the phase
+	if err := n.publish(ctx, "justifications", justifications); err != nil {
+		return nil, fmt.Errorf("publishing justifications: %v", err)
+	}
+	var justificationPosts [][]*wireJustification
+	if err := n.readPhase(ctx, "justifications", &justificationPosts); err != nil {
+		return nil, fmt.Errorf("reading justifications: %v", err)
+	}
+	for _, incomingJustifications := range justificationPosts {
+		for _, wire := range incomingJustifications {
+			if wire.Index == uint32(n.index) {
+				continue // our own justification
+			}
+			justification, err := decodeJustification(n.suite, wire)
+			if err != nil {
+				return nil, fmt.Errorf("decoding justification of trustee %d: %v", wire.Index, err)
+			}
+			if err := generator.ProcessJustification(justification); err != nil {
+				return nil, fmt.Errorf("processing justification of trustee %d: %v", wire.Index, err)
+			}
+		}
+	}
+
+	// creating a share fulfills the purpose of the dkg
+	if !generator.Certified() {
+		return nil, fmt.Errorf("%w: trustee %d", voting.ErrDKGNotCertified, n.index)
+	}
+	distShare, err := generator.DistKeyShare()
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure every trustee ended up with the same public key and commitments
+	result, err := encodeResult(distShare)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.publish(ctx, "results", result); err != nil {
+		return nil, fmt.Errorf("publishing result: %v", err)
+	}
+	var resultPosts []*wireResult
+	if err := n.readPhase(ctx, "results", &resultPosts); err != nil {
+		return nil, fmt.Errorf("reading results: %v", err)
+	}
+	for i, other := range resultPosts {
+		if !sameResult(result, other) {
+			return nil, fmt.Errorf("trustee %d ended the ceremony with a different public key", i)
+		}
+	}
+
+	return distShare, nil
+}
+
+// helper function, the public outcome of the dkg for a share
+func encodeResult(distShare *dkg.DistKeyShare) (*wireResult, error) {
+	publicKey, err := distShare.Public().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	commitments, err := encodePoints(distShare.Commitments())
+	if err != nil {
+		return nil, err
+	}
+	return &wireResult{PublicKey: publicKey, Commitments: commitments}, nil
+}
+
+// helper function, tells whether two trustees reported the same outcome
+func sameResult(a, b *wireResult) bool {
+	if b == nil || !bytes.Equal(a.PublicKey, b.PublicKey) || len(a.Commitments) != len(b.Commitments) {
+		return false
+	}
+	for i := range a.Commitments {
+		if !bytes.Equal(a.Commitments[i], b.Commitments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// publishes this trustee's message for a phase, signed with its long-term key
+func (n *Node) publish(ctx context.Context, phase string, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	signature, err := schnorr.Sign(n.suite, n.secret, postMessage(phase, n.index, body))
+	if err != nil {
+		return err
+	}
+	header := http.Header{signatureHeader: []string{hex.EncodeToString(signature)}}
+	return n.sendBody(ctx, "/phases/"+phase+"?from="+strconv.Itoa(n.index), body, header, nil)
+}
+
+// reads every trustee's message for a phase, waiting until the phase is complete
+// messages are decoded into out, which must point to a slice, in order of trustee index
+func (n *Node) readPhase(ctx context.Context, phase string, out interface{}) error {
+	var posts []post
+	if err := n.poll(ctx, "/phases/"+phase, &posts); err != nil {
+		return err
+	}
+	bodies := make([]json.RawMessage, len(posts))
+	for i, p := range posts {
+		bodies[i] = p.Body
+	}
+	raw, err := json.Marshal(bodies)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// sends a message to the coordinator, decoding the answer into out if it isn't nil
+func (n *Node) send(ctx context.Context, path string, message, out interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return n.sendBody(ctx, path, body, nil, out)
+}
+
+// sends an encoded message to the coordinator, with the given headers, decoding the answer into out if it isn't nil
+func (n *Node) sendBody(ctx context.Context, path string, body []byte, header http.Header, out interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.coordinator+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := n.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		reason, _ := io.ReadAll(response.Body)
+		return fmt.Errorf("coordinator answered %s: %s", response.Status, bytes.TrimSpace(reason))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+// asks the coordinator for a step until it is complete, decoding the answer into out
+func (n *Node) poll(ctx context.Context, path string, out interface{}) error {
+	for {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, n.coordinator+path, nil)
+		if err != nil {
+			return err
+		}
+		response, err := n.client.Do(request)
+		if err != nil {
+			return err
+		}
+		if response.StatusCode == http.StatusOK {
+			err = json.NewDecoder(response.Body).Decode(out)
+			response.Body.Close()
+			return err
+		}
+		reason, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != http.StatusServiceUnavailable {
+			return fmt.Errorf("coordinator answered %s: %s", response.Status, bytes.TrimSpace(reason))
+		}
+
+		// the step is not complete yet, wait for the other trustees
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(n.PollInterval):
+		}
+	}
+}
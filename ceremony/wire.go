@@ -0,0 +1,169 @@
+package ceremony
+
+import (
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the dkg messages hold points and scalars, which are interfaces and can't be decoded from JSON directly
+// these are their counterparts on the wire, with every point and scalar in its binary encoding
+// (encoding/json writes the bytes as base64)
+
+// wireDeal is a dkg.Deal, along with the index of the trustee it is meant for
+// the deal itself is encrypted to that trustee's long-term key, so it is safe to publish
+type wireDeal struct {
+	To        int    // the index of the recipient
+	Index     uint32 // the index of the dealer
+	DHKey     []byte // the ephemeral Diffie-Hellman key of the encrypted deal
+	Signature []byte // the dealer's signature over the encrypted deal
+	Nonce     []byte // the nonce of the encrypted deal
+	Cipher    []byte // the encrypted deal
+	DealSig   []byte // the dealer's signature over the whole dkg deal
+}
+
+// wireJustification is a dkg.Justification
+// the deal inside is revealed in plaintext, as the dealer is answering a complaint about it
+type wireJustification struct {
+	Index       uint32   // the index of the dealer
+	SessionID   []byte   // the session of the vss
+	Verifier    uint32   // the index of the complaining trustee
+	DealSession []byte   // the session of the revealed deal
+	ShareIndex  int      // the index of the revealed share
+	ShareValue  []byte   // the value of the revealed share
+	T           uint32   // the threshold of the revealed deal
+	Commitments [][]byte // the commitments of the revealed deal
+	Signature   []byte   // the dealer's signature over the justification
+}
+
+// wireResult is what a trustee reports once the ceremony is over
+// all trustees must report the same public key and commitments
+type wireResult struct {
+	PublicKey   []byte   // the public key of the election
+	Commitments [][]byte // the public commitments of the dkg, used to verify shadows
+}
+
+// wireParticipants is the list of trustees taking part in the ceremony
+type wireParticipants struct {
+	Suite      string   // the name of the cipher suite of the election
+	Threshold  int      // the number of trustees needed to decrypt
+	PublicKeys [][]byte // the long-term public keys, in order of index
+}
+
+// helper function, decodes a point
+func decodePoint(suite suites.Suite, data []byte) (kyber.Point, error) {
+	point := suite.Point()
+	err := point.UnmarshalBinary(data)
+	return point, err
+}
+
+// helper function, decodes a scalar
+func decodeScalar(suite suites.Suite, data []byte) (kyber.Scalar, error) {
+	scalar := suite.Scalar()
+	err := scalar.UnmarshalBinary(data)
+	return scalar, err
+}
+
+// helper function, encodes a list of points
+func encodePoints(points []kyber.Point) (encoded [][]byte, err error) {
+	encoded = make([][]byte, len(points))
+	for i, point := range points {
+		if encoded[i], err = point.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return // encoded, nil
+}
+
+// helper function, decodes a list of points
+func decodePoints(suite suites.Suite, encoded [][]byte) (points []kyber.Point, err error) {
+	points = make([]kyber.Point, len(encoded))
+	for i, data := range encoded {
+		if points[i], err = decodePoint(suite, data); err != nil {
+			return nil, err
+		}
+	}
+	return // points, nil
+}
+
+// converts a deal to its wire format
+func encodeDeal(to int, deal *dkg.Deal) (*wireDeal, error) {
+	return &wireDeal{
+		To:        to,
+		Index:     deal.Index,
+		DHKey:     deal.Deal.DHKey, // already encoded, it is what the dealer signs
+		Signature: deal.Deal.Signature,
+		Nonce:     deal.Deal.Nonce,
+		Cipher:    deal.Deal.Cipher,
+		DealSig:   deal.Signature,
+	}, nil
+}
+
+// converts a deal back from its wire format
+func decodeDeal(suite suites.Suite, wire *wireDeal) (*dkg.Deal, error) {
+	if _, err := decodePoint(suite, wire.DHKey); err != nil {
+		return nil, err
+	}
+	return &dkg.Deal{
+		Index: wire.Index,
+		Deal: &vss.EncryptedDeal{
+			DHKey:     wire.DHKey,
+			Signature: wire.Signature,
+			Nonce:     wire.Nonce,
+			Cipher:    wire.Cipher,
+		},
+		Signature: wire.DealSig,
+	}, nil
+}
+
+// converts a justification to its wire format
+func encodeJustification(justification *dkg.Justification) (*wireJustification, error) {
+	deal := justification.Justification.Deal
+	shareValue, err := deal.SecShare.V.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	commitments, err := encodePoints(deal.Commitments)
+	if err != nil {
+		return nil, err
+	}
+	return &wireJustification{
+		Index:       justification.Index,
+		SessionID:   justification.Justification.SessionID,
+		Verifier:    justification.Justification.Index,
+		DealSession: deal.SessionID,
+		ShareIndex:  deal.SecShare.I,
+		ShareValue:  shareValue,
+		T:           deal.T,
+		Commitments: commitments,
+		Signature:   justification.Justification.Signature,
+	}, nil
+}
+
+// converts a justification back from its wire format
+func decodeJustification(suite suites.Suite, wire *wireJustification) (*dkg.Justification, error) {
+	shareValue, err := decodeScalar(suite, wire.ShareValue)
+	if err != nil {
+		return nil, err
+	}
+	commitments, err := decodePoints(suite, wire.Commitments)
+	if err != nil {
+		return nil, err
+	}
+	return &dkg.Justification{
+		Index: wire.Index,
+		Justification: &vss.Justification{
+			SessionID: wire.SessionID,
+			Index:     wire.Verifier,
+			Deal: &vss.Deal{
+				SessionID:   wire.DealSession,
+				SecShare:    &share.PriShare{I: wire.ShareIndex, V: shareValue},
+				T:           wire.T,
+				Commitments: commitments,
+			},
+			Signature: wire.Signature,
+		},
+	}, nil
+}
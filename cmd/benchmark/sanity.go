@@ -0,0 +1,1229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+
+	"github.com/SpencerBouck/crypto-voting/board"
+	"github.com/SpencerBouck/crypto-voting/ceremony"
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// the suites the self-tests run on
+// suites whose points can't hold ballots are skipped
+var sanitySuites = []string{"ed25519", "P256", "Residue512"}
+
+// runs every self-test, from the key ceremony to the decryption, on each suite in turn
+func doSanityTests() {
+	tested := make([]suites.Suite, 0, len(sanitySuites))
+	for _, name := range sanitySuites {
+		suite, err := voting.FindSuite(name)
+		if err != nil {
+			log.Printf("Skipping suite %s: %v", name, err)
+			continue
+		}
+		log.Printf("Running self-tests on %s", suite)
+		doShuffleTest(suite, 8)           // shuffle with a single secret key
+		doThresholdTest(suite, 8, 5, 3)   // decrypt with the distributed shares
+		doParallelTest(suite, 8, 5, 3)    // decrypt on a pool of workers, in the same order
+		doCeremonyTest(suite, 8, 5, 3)    // run the key ceremony over localhost
+		doStorageTest(suite, 8, 5, 3)     // save and load the shares
+		doEncodingTest(suite, 8)          // encode and decode ballots
+		doIngestionTest(suite, 8)         // reject copied ballots before mixing
+		doChoiceTest(suite, 8)            // reject ballots for someone who isn't a candidate
+		doRegistryTest(suite, 8)          // reject ballots from voters who aren't registered, or vote twice
+		doHomomorphicTest(suite, 8, 5, 3) // tally without mixing
+		doMixnetTest(suite, 8, 3)         // mix through a cascade of servers
+		doVectorTest(suite, 5, 5, 3)      // mix long messages with their chunks kept together
+		doReassemblyTest(suite)           // put long messages back together, reporting the broken ones
+		doSchemaTest(suite)               // encode typed selections into long ballots, and reject malformed ones
+		doTallyTest(suite, 6, 5, 3)       // count typed ballots per question, and sign the result
+		doElectionTest(suite, 8, 5, 3)    // run an election through its phases, posting to a bulletin board
+		doSingleBallotTest(suite, 5, 3)   // count an election with a single ballot, which can't be mixed
+		tested = append(tested, suite)
+	}
+
+	// artifacts of one suite are refused by another
+	for i := 1; i < len(tested); i++ {
+		doSuiteMismatchTest(tested[i-1], tested[i])
+	}
+	log.Printf("Self-tests passed")
+}
+
+// helper function, picks a fresh key pair to encrypt the messages of a self-test with
+func sanityKeys(suite suites.Suite) (private kyber.Scalar, public kyber.Point) {
+	private = suite.Scalar().Pick(suite.RandomStream())
+	public = suite.Point().Mul(private, nil)
+	return // private, public
+}
+
+// make sure a ballot cast under one suite doesn't verify under another
+func doSuiteMismatchTest(suite, other suites.Suite) {
+
+	_, h := sanityKeys(suite) // the public key
+
+	messages, _, _ := voting.GenerateMessageEncryptions(suite, 1, h)
+	ballot := voting.EncryptBallot(suite, messages, h, "sanity", "voter")
+	check(ballot.Verify(suite, "sanity"))
+	if !errors.Is(ballot.Verify(other, "sanity"), voting.ErrSuiteMismatch) {
+		panic(fmt.Sprintf("A %s ballot was verified under %s!", suite, other))
+	}
+}
+
+// preform a shuffle test
+func doShuffleTest(suite suites.Suite, listLength int) {
+
+	a, h := sanityKeys(suite) // the private and public key
+
+	messages, elGamal1, elGamal2 := voting.GenerateMessageEncryptions(suite, listLength, h)       // generate messages
+	newElGamal1, newElGamal2, record, err := voting.ShuffleAndCheck(suite, h, elGamal1, elGamal2) // shuffle them
+	check(err)
+	decryptedMessages, err := voting.DecryptAll(suite, newElGamal1, newElGamal2, a) // decrypt the shuffled messages
+	check(err)
+	check(voting.CheckDecryption(messages, decryptedMessages)) // verify correct decryption
+
+	// the record of the shuffle can be published, and checked later by anyone
+	dir, err := os.MkdirTemp("", "crypto-voting")
+	check(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "shuffle.json")
+	check(voting.SaveMixRecord(path, record))
+	loaded, err := voting.LoadMixRecord(path)
+	check(err)
+	check(loaded.Verify(suite))
+
+	// a record whose output was tampered with doesn't verify
+	loaded.Output[0], loaded.Output[len(loaded.Output)-1] = loaded.Output[len(loaded.Output)-1], loaded.Output[0]
+	if listLength > 1 && !errors.Is(loaded.Verify(suite), voting.ErrInvalidShuffleProof) {
+		panic("Tampered shuffle record was verified!")
+	}
+
+	// a single pair, or none, can't be shuffled, and a record claiming to shuffle one doesn't verify
+	for _, n := range []int{0, 1} {
+		if _, _, _, err := voting.ShuffleAndCheck(suite, h, elGamal1[:n], elGamal2[:n]); !errors.Is(err, voting.ErrInvalidShuffleProof) {
+			panic(fmt.Sprintf("Shuffling %d pairs returned %v", n, err))
+		}
+	}
+	single, err := voting.JoinCiphertexts(elGamal1[:1], elGamal2[:1])
+	check(err)
+	if _, err := voting.JoinCiphertexts(elGamal1, elGamal2[:1]); err == nil {
+		panic("Halves of different lengths were joined!")
+	}
+	if _, err := voting.DecryptAll(suite, elGamal1, elGamal2[:1], a); err == nil {
+		panic("Halves of different lengths were decrypted!")
+	}
+	singleRecord := &voting.MixRecord{Suite: suite.String(), PublicKey: h, Input: single, Output: single, Proof: record.Proof}
+	if err := singleRecord.Verify(suite); !errors.Is(err, voting.ErrInvalidShuffleProof) {
+		panic(fmt.Sprintf("Record of a single pair returned %v", err))
+	}
+	check(voting.SaveMixRecord(path, singleRecord))
+	if _, err := voting.LoadMixRecord(path); !errors.Is(err, voting.ErrInvalidShuffleProof) {
+		panic(fmt.Sprintf("Loading the record of a single pair returned %v", err))
+	}
+}
+
+// preform a threshold test
+// reading this function will provide a high-level understanding of the scheme used
+func doThresholdTest(suite suites.Suite, listLength, contributorCount, threshold int) {
+
+	// create the environment
+	// each user gets a share, which contains:
+	// 1) a portion of the secret key
+	// 2) the public key
+	// for the cryptosystem
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+
+	// each user's share will contain the public key
+	// Since these are all the same, we choose to use the copy at index 0 arbitrarlily
+	publicKey := shares[0].Public()
+
+	// generate messages
+	messages, elGamal1, elGamal2 := voting.GenerateMessageEncryptions(suite, listLength, publicKey)
+
+	// --------------------------------------------------------- //
+	//                     Decryption Begins                     //
+	// --------------------------------------------------------  //
+
+	// decrypt the messages, using the distributed shares
+	decryptedMessages, misbehaving, err := voting.DecryptMessages(suite, elGamal1, elGamal2, shares, threshold, contributorCount)
+	check(err)
+	if len(misbehaving) > 0 {
+		panic(fmt.Sprintf("Honest trustees %v were reported as misbehaving", misbehaving))
+	}
+
+	// assures all decryptions are correct
+	check(voting.CheckDecryption(messages, decryptedMessages))
+
+	// a copy of a shadow is harmless, but a second, different shadow from the same trustee is faulty
+	shadows := make([]*voting.VerifiableShadow, len(shares))
+	for i, distShare := range shares {
+		shadows[i], err = voting.ExtractShadow(suite, elGamal1[0], elGamal2[0], distShare)
+		check(err)
+	}
+	conflicting := &voting.VerifiableShadow{Shadow: &share.PubShare{I: shadows[0].Shadow.I, V: suite.Point().Pick(suite.RandomStream())}, Proof: shadows[0].Proof}
+	valid, faulty := voting.VerifyShadows(suite, elGamal1[0], append(shadows, shadows[0], conflicting), shares[0].Commits, contributorCount)
+	if len(valid) != contributorCount || len(faulty) != 1 || faulty[0] != shadows[0].Shadow.I {
+		panic(fmt.Sprintf("Duplicate shadows gave %d valid shadows, and faulty trustees %v", len(valid), faulty))
+	}
+
+	// take all but threshold trustees offline, the rest should still be able to decrypt
+	online := make([]*vss.DistKeyShare, len(shares))
+	copy(online[contributorCount-threshold:], shares[contributorCount-threshold:])
+	decryptedMessages, _, err = voting.DecryptMessages(suite, elGamal1, elGamal2, online, threshold, contributorCount)
+	check(err)
+	check(voting.CheckDecryption(messages, decryptedMessages))
+
+	// one more trustee offline, and there aren't enough of them left
+	online[contributorCount-threshold] = nil
+	if _, _, err := voting.DecryptMessages(suite, elGamal1, elGamal2, online, threshold, contributorCount); !errors.Is(err, voting.ErrInsufficientShadows) {
+		panic(fmt.Sprintf("Decryption below the threshold returned %v", err))
+	}
+}
+
+// preform a key ceremony between trustee nodes over localhost,
+// then encrypt and decrypt with the shares it produced
+func doCeremonyTest(suite suites.Suite, listLength, contributorCount, threshold int) {
+
+	shares, err := ceremony.RunLocal(context.Background(), suite, contributorCount, threshold)
+	check(err)
+	publicKey := shares[0].Public()
+
+	messages, elGamal1, elGamal2 := voting.GenerateMessageEncryptions(suite, listLength, publicKey)
+	decryptedMessages, _, err := voting.DecryptMessages(suite, elGamal1, elGamal2, shares, threshold, contributorCount)
+	check(err)
+	check(voting.CheckDecryption(messages, decryptedMessages))
+}
+
+// preform a round trip of the shares through encrypted files,
+// then decrypt with the shares that were read back
+func doStorageTest(suite suites.Suite, listLength, contributorCount, threshold int) {
+
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+	publicKey := shares[0].Public()
+
+	dir, err := os.MkdirTemp("", "shares")
+	check(err)
+	defer os.RemoveAll(dir)
+
+	password := []byte("correct horse battery staple")
+	loadedShares := make([]*vss.DistKeyShare, len(shares))
+	for i, distShare := range shares {
+		path := filepath.Join(dir, fmt.Sprintf("share%d.json", i))
+		check(voting.SaveShare(suite, path, distShare, password))
+		if _, err := voting.LoadShare(suite, path, []byte("wrong password")); err == nil {
+			panic("Share decrypted with the wrong password!")
+		}
+		loadedShares[i], err = voting.LoadShare(suite, path, password)
+		check(err)
+	}
+
+	// a share asking for an absurd number of iterations is refused before they are run
+	data, err := os.ReadFile(filepath.Join(dir, "share0.json"))
+	check(err)
+	stored := make(map[string]interface{})
+	check(json.Unmarshal(data, &stored))
+	stored["Iterations"] = 1 << 40
+	data, err = json.Marshal(stored)
+	check(err)
+	tampered := filepath.Join(dir, "tampered.json")
+	check(os.WriteFile(tampered, data, 0644))
+	if _, err := voting.LoadShare(suite, tampered, password); err == nil || errors.Is(err, voting.ErrWrongPassword) {
+		panic(fmt.Sprintf("Share with 2^40 iterations returned %v", err))
+	}
+
+	// a share with a truncated nonce is refused as malformed, not as a wrong password
+	check(json.Unmarshal(data, &stored))
+	stored["Iterations"] = 100000
+	stored["Nonce"] = []byte{1, 2, 3}
+	data, err = json.Marshal(stored)
+	check(err)
+	check(os.WriteFile(tampered, data, 0644))
+	if _, err := voting.LoadShare(suite, tampered, password); err == nil || errors.Is(err, voting.ErrWrongPassword) {
+		panic(fmt.Sprintf("Share with a 3 byte nonce returned %v", err))
+	}
+
+	messages, elGamal1, elGamal2 := voting.GenerateMessageEncryptions(suite, listLength, publicKey)
+	decryptedMessages, _, err := voting.DecryptMessages(suite, elGamal1, elGamal2, loadedShares, threshold, contributorCount)
+	check(err)
+	check(voting.CheckDecryption(messages, decryptedMessages))
+}
+
+// preform a round trip of ballots through their binary and JSON encodings
+func doEncodingTest(suite suites.Suite, listLength int) {
+
+	_, h := sanityKeys(suite) // the public key
+
+	_, elGamal1, elGamal2 := voting.GenerateMessageEncryptions(suite, listLength, h)
+	ciphertexts, err := voting.JoinCiphertexts(elGamal1, elGamal2)
+	check(err)
+	ballot := voting.NewBallot(suite, "sanity", ciphertexts...)
+	hash, err := ballot.Hash()
+	check(err)
+
+	// binary
+	data, err := ballot.MarshalBinary()
+	check(err)
+	decoded := &voting.Ballot{}
+	check(decoded.UnmarshalBinary(data))
+	decodedHash, err := decoded.Hash()
+	check(err)
+	if !bytes.Equal(hash, decodedHash) {
+		panic("Ballot changed through its binary encoding!")
+	}
+
+	// JSON
+	data, err = json.Marshal(ballot)
+	check(err)
+	decoded = &voting.Ballot{}
+	check(json.Unmarshal(data, decoded))
+	decodedHash, err = decoded.Hash()
+	check(err)
+	if !bytes.Equal(hash, decodedHash) {
+		panic("Ballot changed through its JSON encoding!")
+	}
+}
+
+// preform an ingestion test: cast ballots with proofs of encryption,
+// make sure copied and tampered ballots are rejected, then mix and decrypt the rest
+func doIngestionTest(suite suites.Suite, listLength int) {
+
+	a, h := sanityKeys(suite) // the private and public key
+
+	messages, _, _ := voting.GenerateMessageEncryptions(suite, listLength, h)
+	ballots := make([]*voting.Ballot, 0, listLength+2)
+	for i, message := range messages {
+		ballots = append(ballots, voting.EncryptBallot(suite, []kyber.Point{message}, h, "sanity", fmt.Sprintf("voter%d", i)))
+	}
+
+	// a voter copies another voter's ballot under their own name
+	copied := *ballots[0]
+	copied.VoterID = "copycat"
+	ballots = append(ballots, &copied)
+
+	// a voter resubmits another voter's ballot as is
+	ballots = append(ballots, ballots[1])
+
+	accepted, rejected := voting.IngestBallots(suite, "sanity", ballots)
+	if len(accepted) != listLength || len(rejected) != 2 {
+		panic(fmt.Sprintf("Ingestion accepted %d ballots and rejected %d!", len(accepted), len(rejected)))
+	}
+
+	elGamal1, elGamal2, err := voting.BallotCiphertexts(accepted)
+	check(err)
+	elGamal1, elGamal2, _, err = voting.ShuffleAndCheck(suite, h, elGamal1, elGamal2)
+	check(err)
+	decrypted, err := voting.DecryptAll(suite, elGamal1, elGamal2, a)
+	check(err)
+	check(voting.CheckDecryption(messages, decrypted))
+}
+
+// preform a candidate-list test: cast ballots proving they hold one of the candidates,
+// make sure a ballot for someone else is rejected, then mix and decrypt the rest
+func doChoiceTest(suite suites.Suite, listLength int) {
+
+	a, h := sanityKeys(suite) // the private and public key
+
+	names := []string{"Smith", "Queen", "Jones"}
+	candidates, err := voting.EncodeCandidates(suite, names)
+	check(err)
+
+	ballots := make([]*voting.Ballot, 0, listLength+1)
+	messages := make([]kyber.Point, 0, listLength)
+	for i := 0; i < listLength; i++ {
+		choice := i % len(candidates)
+		ballot, err := voting.EncryptChoiceBallot(suite, []int{choice}, candidates, h, "sanity", fmt.Sprintf("voter%d", i))
+		check(err)
+		ballots = append(ballots, ballot)
+		messages = append(messages, candidates[choice])
+	}
+
+	// a voter votes for a write-in, and claims it is the first candidate
+	voterID := "cheater"
+	writeIn, err := voting.EncodeCandidate(suite, "Mallory")
+	check(err)
+	elGamal1, elGamal2, randomness := voting.EncryptMessageWithRandomness(suite, writeIn, h)
+	ciphertext := &voting.Ciphertext{C1: elGamal1, C2: elGamal2}
+	choiceProof, err := voting.ProveChoice(suite, ciphertext, randomness, 0, candidates, h, "sanity", voterID)
+	check(err)
+	cheating := &voting.Ballot{
+		Suite:        suite.String(),
+		ElectionID:   "sanity",
+		VoterID:      voterID,
+		Ciphertexts:  []*voting.Ciphertext{ciphertext},
+		Proofs:       []*voting.EncryptionProof{voting.ProveEncryption(suite, ciphertext, randomness, "sanity", voterID)},
+		ChoiceProofs: []*voting.ChoiceProof{choiceProof},
+	}
+	ballots = append(ballots, cheating)
+
+	// bad input is refused with an error, not a panic
+	if _, err := voting.ProveChoice(suite, ciphertext, randomness, len(candidates), candidates, h, "sanity", voterID); err == nil {
+		panic("Proved the choice of a candidate that doesn't exist!")
+	}
+	broken := &voting.Ballot{Suite: suite.String(), ElectionID: "sanity", Ciphertexts: []*voting.Ciphertext{nil}, Proofs: cheating.Proofs}
+	if _, err := broken.MarshalBinary(); err == nil {
+		panic("Encoded a ballot with a missing ciphertext!")
+	}
+	if err := broken.Verify(suite, "sanity"); !errors.Is(err, voting.ErrInvalidEncryptionProof) {
+		panic(fmt.Sprintf("Ballot with a missing ciphertext returned %v", err))
+	}
+
+	accepted, rejected := voting.IngestChoiceBallots(suite, "sanity", candidates, h, ballots)
+	if len(accepted) != listLength || !errors.Is(rejected[listLength], voting.ErrInvalidChoiceProof) {
+		panic(fmt.Sprintf("Ingestion accepted %d ballots and rejected %d!", len(accepted), len(rejected)))
+	}
+
+	elGamal1s, elGamal2s, err := voting.BallotCiphertexts(accepted)
+	check(err)
+	elGamal1s, elGamal2s, _, err = voting.ShuffleAndCheck(suite, h, elGamal1s, elGamal2s)
+	check(err)
+	decrypted, err := voting.DecryptAll(suite, elGamal1s, elGamal2s, a)
+	check(err)
+	check(voting.CheckDecryption(messages, decrypted))
+}
+
+// preform an eligibility test: registered voters sign their ballots,
+// make sure unknown voters, forged signatures and second ballots are rejected under either re-voting policy
+func doRegistryTest(suite suites.Suite, listLength int) {
+
+	a, h := sanityKeys(suite) // the private and public key
+
+	for _, policy := range []voting.RevotePolicy{voting.FirstVoteCounts, voting.LastVoteCounts} {
+		registry := voting.NewRegistry(suite, "sanity", policy)
+		keys := make([]kyber.Scalar, listLength)
+		for i := range keys {
+			var public kyber.Point
+			keys[i], public = voting.NewVoterKey(suite)
+			check(registry.Register(fmt.Sprintf("voter%d", i), public))
+		}
+		if err := registry.Register("voter0", h); err == nil {
+			panic("A voter was registered twice!")
+		}
+
+		messages, _, _ := voting.GenerateMessageEncryptions(suite, listLength+1, h)
+		ballots := make([]*voting.Ballot, 0, listLength+3)
+		for i := 0; i < listLength; i++ {
+			ballot := voting.EncryptBallot(suite, []kyber.Point{messages[i]}, h, "sanity", fmt.Sprintf("voter%d", i))
+			check(ballot.Sign(suite, keys[i]))
+			ballots = append(ballots, ballot)
+		}
+
+		// a stranger signs with their own key, a voter signs in another voter's name, and a voter votes again
+		stranger, _ := voting.NewVoterKey(suite)
+		unknown := voting.EncryptBallot(suite, []kyber.Point{messages[0]}, h, "sanity", "stranger")
+		check(unknown.Sign(suite, stranger))
+		forged := voting.EncryptBallot(suite, []kyber.Point{messages[1]}, h, "sanity", "voter1")
+		check(forged.Sign(suite, keys[2]))
+		again := voting.EncryptBallot(suite, []kyber.Point{messages[listLength]}, h, "sanity", "voter0")
+		check(again.Sign(suite, keys[0]))
+		ballots = append(ballots, unknown, forged, again)
+
+		// the signature covers the whole ballot, changing the voter breaks it
+		data, err := ballots[1].MarshalBinary()
+		check(err)
+		decoded := &voting.Ballot{}
+		check(decoded.UnmarshalBinary(data))
+		check(registry.VerifyBallot(suite, decoded))
+		decoded.VoterID = "voter3"
+		if err := registry.VerifyBallot(suite, decoded); !errors.Is(err, voting.ErrInvalidBallotSignature) {
+			panic(fmt.Sprintf("Ballot moved to another voter returned %v", err))
+		}
+
+		accepted, rejected := voting.IngestSignedBallots(suite, registry, ballots, nil)
+		if len(accepted) != listLength || len(rejected) != 3 ||
+			!errors.Is(rejected[listLength], voting.ErrUnknownVoter) || !errors.Is(rejected[listLength+1], voting.ErrInvalidBallotSignature) {
+			panic(fmt.Sprintf("Ingestion under %s accepted %d ballots and rejected %v!", policy, len(accepted), rejected))
+		}
+		expected := messages[:listLength]
+		if policy == voting.FirstVoteCounts {
+			if !errors.Is(rejected[listLength+2], voting.ErrAlreadyVoted) || accepted[0] != ballots[0] {
+				panic(fmt.Sprintf("Second ballot under %s returned %v", policy, rejected[listLength+2]))
+			}
+		} else {
+			if !errors.Is(rejected[0], voting.ErrSupersededBallot) || accepted[len(accepted)-1] != again {
+				panic(fmt.Sprintf("First ballot under %s returned %v", policy, rejected[0]))
+			}
+			expected = append(append([]kyber.Point(nil), messages[1:listLength]...), messages[listLength])
+		}
+
+		elGamal1, elGamal2, err := voting.BallotCiphertexts(accepted)
+		check(err)
+		elGamal1, elGamal2, _, err = voting.ShuffleAndCheck(suite, h, elGamal1, elGamal2)
+		check(err)
+		decrypted, err := voting.DecryptAll(suite, elGamal1, elGamal2, a)
+		check(err)
+		check(voting.CheckDecryption(expected, decrypted))
+	}
+}
+
+// preform a parallel decryption: the output must match the serial one exactly, whatever the number of workers,
+// and a cancelled decryption must stop
+func doParallelTest(suite suites.Suite, listLength, contributorCount, threshold int) {
+
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+	messages, elGamal1, elGamal2 := voting.GenerateMessageEncryptions(suite, listLength, shares[0].Public())
+
+	serial, _, err := voting.DecryptMessages(suite, elGamal1, elGamal2, shares, threshold, contributorCount)
+	check(err)
+	for _, workers := range []int{0, 1, 3, 2 * listLength * contributorCount} {
+		decryptedMessages, misbehaving, err := voting.DecryptMessagesParallel(context.Background(), suite, elGamal1, elGamal2, shares, threshold, contributorCount, workers)
+		check(err)
+		if len(misbehaving) > 0 {
+			panic(fmt.Sprintf("Honest trustees %v were reported as misbehaving", misbehaving))
+		}
+		for i := range serial {
+			if !decryptedMessages[i].Equal(serial[i]) {
+				panic(fmt.Sprintf("Message %d decrypted on %d workers doesn't match the serial decryption!", i, workers))
+			}
+		}
+	}
+	check(voting.CheckDecryption(messages, serial))
+
+	// with too few trustees, both fail the same way
+	online := make([]*vss.DistKeyShare, len(shares))
+	copy(online, shares[:threshold-1])
+	if _, _, err := voting.DecryptMessagesParallel(context.Background(), suite, elGamal1, elGamal2, online, threshold, contributorCount, 0); !errors.Is(err, voting.ErrInsufficientShadows) {
+		panic(fmt.Sprintf("Parallel decryption with too few trustees returned %v", err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := voting.DecryptMessagesParallel(ctx, suite, elGamal1, elGamal2, shares, threshold, contributorCount, 0); !errors.Is(err, context.Canceled) {
+		panic(fmt.Sprintf("Cancelled decryption returned %v", err))
+	}
+}
+
+// preform a long message test: every message is a vector of chunks, shuffled as a unit,
+// and decryption gives back whole messages; chunks moved from one vector to another are caught
+func doVectorTest(suite suites.Suite, listLength, contributorCount, threshold int) {
+
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+	publicKey := shares[0].Public()
+
+	texts := make([]string, listLength)
+	vectors := make([]voting.CiphertextVector, listLength)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("Ballot #%d: I vote for Smith, and the park should be extended.", i)
+		_, vectors[i], err = voting.EncryptLongMessageVector(suite, []byte(texts[i]), publicKey)
+		check(err)
+	}
+
+	shuffled, record, err := voting.ShuffleVectorsAndCheck(suite, publicKey, vectors)
+	check(err)
+
+	// the record can be published, and checked by anyone
+	data, err := json.Marshal(record)
+	check(err)
+	published := &voting.VectorMixRecord{}
+	check(json.Unmarshal(data, published))
+	check(published.Verify(suite))
+
+	// moving a chunk from one vector to another breaks the proof
+	published.Output[0][1], published.Output[1][1] = published.Output[1][1], published.Output[0][1]
+	if err := published.Verify(suite); !errors.Is(err, voting.ErrInvalidShuffleProof) {
+		panic(fmt.Sprintf("Shuffle with chunks moved between vectors returned %v", err))
+	}
+
+	// a single vector can't be shuffled, and a record claiming to shuffle one doesn't verify
+	if _, _, err := voting.ShuffleVectorsAndCheck(suite, publicKey, vectors[:1]); !errors.Is(err, voting.ErrInvalidShuffleProof) {
+		panic(fmt.Sprintf("Shuffling a single vector returned %v", err))
+	}
+	single := &voting.VectorMixRecord{Suite: suite.String(), PublicKey: publicKey, Input: vectors[:1], Output: vectors[:1], Proof: record.Proof}
+	if err := single.Verify(suite); !errors.Is(err, voting.ErrInvalidShuffleProof) {
+		panic(fmt.Sprintf("Record of a single vector returned %v", err))
+	}
+
+	compiled, misbehaving, err := voting.DecryptLongMessages(suite, shuffled, shares, threshold, contributorCount)
+	check(err)
+	if len(misbehaving) > 0 {
+		panic(fmt.Sprintf("Honest trustees %v were reported as misbehaving", misbehaving))
+	}
+	found := make(map[string]bool)
+	for _, message := range compiled {
+		found[message] = true // messages come back exactly, without padding
+	}
+	for _, text := range texts {
+		if !found[text] {
+			panic(fmt.Sprintf("Message %q wasn't decrypted!", text))
+		}
+	}
+
+	// a vector holding a chunk of another message doesn't compile
+	decrypted, _, err := voting.DecryptVectors(suite, published.Output[:2], shares, threshold, contributorCount)
+	check(err)
+	if _, err := voting.CompileVector(decrypted[0]); !errors.Is(err, voting.ErrEmbedding) {
+		panic(fmt.Sprintf("Vector with another message's chunk returned %v", err))
+	}
+
+	// a message takes only the chunks it needs, unless a fixed number is asked for
+	text := []byte(texts[0])
+	portions, _, _, err := voting.EncryptLongMessageChunks(suite, text, publicKey, 0)
+	check(err)
+	if len(portions) != voting.LongMessageChunks(suite, len(text)) {
+		panic(fmt.Sprintf("Message of %d bytes took %d chunks", len(text), len(portions)))
+	}
+	if _, _, _, err := voting.EncryptLongMessageChunks(suite, text, publicKey, len(portions)-1); !errors.Is(err, voting.ErrMessageTooLong) {
+		panic(fmt.Sprintf("Message too long for its chunks returned %v", err))
+	}
+	if _, _, _, err := voting.EncryptLongMessage(suite, make([]byte, 100*suite.Point().EmbedLen()), publicKey); !errors.Is(err, voting.ErrMessageTooLong) {
+		panic(fmt.Sprintf("Message too long for an election returned %v", err))
+	}
+
+	// a truncated message, or one whose data was changed, doesn't compile
+	if _, err := voting.CompileVector(portions[:len(portions)-1]); !errors.Is(err, voting.ErrCorruptMessage) {
+		panic(fmt.Sprintf("Truncated message returned %v", err))
+	}
+	data, err = portions[0].Data()
+	check(err)
+	data[len(data)-1] ^= 1
+	tampered := append([]kyber.Point{suite.Point().Embed(data, suite.RandomStream())}, portions[1:]...)
+	if _, err := voting.CompileVector(tampered); !errors.Is(err, voting.ErrCorruptMessage) {
+		panic(fmt.Sprintf("Tampered message returned %v", err))
+	}
+	compiledText, err := voting.CompileVector(portions)
+	check(err)
+	if compiledText != texts[0] {
+		panic(fmt.Sprintf("Message %q compiled to %q", texts[0], compiledText))
+	}
+}
+
+// put long messages back together from their chunks in any order,
+// telling apart complete, duplicated, incomplete and conflicting ballots
+func doReassemblyTest(suite suites.Suite) {
+
+	_, h := sanityKeys(suite) // the public key, only the plaintexts are used
+
+	texts := []string{"I vote for Smith.", "I vote for Jones.", "I vote for Brown.", "I vote for Green."}
+	ballots := make([][]kyber.Point, len(texts))
+	for i, text := range texts {
+		portions, _, _, err := voting.EncryptLongMessage(suite, []byte(text), h)
+		check(err)
+		ballots[i] = portions
+	}
+
+	// ballot 1 has a chunk twice, ballot 2 misses a chunk, ballot 3 has two versions of a chunk
+	data, err := ballots[3][1].Data()
+	check(err)
+	data[len(data)-1] ^= 1
+	portions := make([]kyber.Point, 0)
+	portions = append(portions, ballots[0]...)
+	portions = append(portions, ballots[1]...)
+	portions = append(portions, ballots[1][2])
+	portions = append(portions, ballots[2][1:]...)
+	portions = append(portions, ballots[3]...)
+	portions = append(portions, suite.Point().Embed(data, suite.RandomStream()))
+
+	report, err := voting.ReassembleMessages(portions, voting.DefaultReassemblyPolicy)
+	check(err)
+	expected := map[string]voting.ReassemblyStatus{
+		texts[0]: voting.ReassemblyComplete,
+		texts[1]: voting.ReassemblyDuplicated,
+		texts[2]: voting.ReassemblyIncomplete,
+		texts[3]: voting.ReassemblyConflicting,
+	}
+	if len(report.Ballots) != len(texts) {
+		panic(fmt.Sprintf("%d ballots were found instead of %d", len(report.Ballots), len(texts)))
+	}
+	for i, text := range texts {
+		prefix, err := ballots[i][0].Data()
+		check(err)
+		for _, ballot := range report.Ballots {
+			if bytes.HasPrefix(prefix, ballot.Prefix) && ballot.Status != expected[text] {
+				panic(fmt.Sprintf("Ballot %q was found %s, not %s", text, ballot.Status, expected[text]))
+			}
+		}
+	}
+	if len(report.Messages) != 2 || report.Err() == nil {
+		panic(fmt.Sprintf("Default policy kept %q, and reported %v", report.Messages, report.Err()))
+	}
+
+	// the outcome doesn't depend on the order of the portions
+	reversed := make([]kyber.Point, len(portions))
+	for i := range portions {
+		reversed[len(portions)-1-i] = portions[i]
+	}
+	again, err := voting.ReassembleMessages(reversed, voting.DefaultReassemblyPolicy)
+	check(err)
+	for i := range report.Ballots {
+		if again.Ballots[i].Status != report.Ballots[i].Status || again.Ballots[i].Message != report.Ballots[i].Message {
+			panic("Reassembly depends on the order of the portions")
+		}
+	}
+
+	// the strict policy refuses broken ballots, and asks for none to be kept
+	if _, err := voting.CompileMessages(portions); !errors.Is(err, voting.ErrCorruptMessage) && !errors.Is(err, voting.ErrConflictingChunks) {
+		panic(fmt.Sprintf("Broken ballots were compiled, returning %v", err))
+	}
+	if _, err := voting.ReassembleMessages(portions, voting.ReassemblyPolicy{Incomplete: voting.KeepBallot}); err == nil {
+		panic("A policy keeping incomplete ballots was accepted")
+	}
+	compiled, err := voting.CompileMessages(append(ballots[0], ballots[1]...))
+	check(err)
+	if len(compiled) != 2 {
+		panic(fmt.Sprintf("%d messages were compiled instead of 2", len(compiled)))
+	}
+}
+
+// encode ballots of a schema into long messages and back, and reject selections that break its rules
+func doSchemaTest(suite suites.Suite) {
+
+	_, h := sanityKeys(suite) // the public key, only the plaintexts are used
+
+	schema := sanitySchema()
+	check(schema.Validate())
+
+	// every ballot takes the same number of chunks, and reads back as it was filled in
+	selections := []*voting.Selection{{Candidates: []int{1}}, {Candidates: []int{0, 2}, WriteIns: []string{"Green"}}}
+	portions, _, err := schema.EncryptSelections(suite, selections, h)
+	check(err)
+	blank, _, err := schema.EncryptSelections(suite, []*voting.Selection{{Candidates: []int{0}}, nil}, h)
+	check(err)
+	if len(portions) != schema.Chunks(suite) || len(blank) != len(portions) {
+		panic(fmt.Sprintf("Ballots took %d and %d chunks instead of %d", len(portions), len(blank), schema.Chunks(suite)))
+	}
+	decoded, err := schema.DecodeVector(portions)
+	check(err)
+	if schema.Format(decoded) != schema.Format(selections) {
+		panic(fmt.Sprintf("Selections %q were decoded as %q", schema.Format(selections), schema.Format(decoded)))
+	}
+
+	// selections breaking the rules of the schema are refused
+	invalid := [][]*voting.Selection{
+		{{Candidates: []int{1}}},                                                // a question isn't answered
+		{{Candidates: []int{3}}, nil},                                           // there's no such candidate
+		{{Candidates: []int{0, 1}}, nil},                                        // too many candidates for the question
+		{nil, nil},                                                              // the first question can't be left blank
+		{{Candidates: []int{0}}, {Candidates: []int{2, 0}}},                     // candidates out of order
+		{{Candidates: []int{0}}, {WriteIns: []string{"A", "B"}}},                // too many write-ins
+		{{Candidates: []int{0}}, {WriteIns: []string{strings.Repeat("A", 40)}}}, // write-in too long
+	}
+	for i, bad := range invalid {
+		if _, err := schema.EncodeSelections(bad); !errors.Is(err, voting.ErrInvalidSelection) {
+			panic(fmt.Sprintf("Invalid selections %d returned %v", i, err))
+		}
+	}
+
+	// plaintexts that aren't encodings of selections are refused
+	data, err := schema.EncodeSelections(selections)
+	check(err)
+	other := sanitySchema()
+	other.ElectionID = "another election"
+	malformed := [][]byte{
+		data[:len(data)-1],                   // truncated
+		append(append([]byte{}, data...), 0), // trailing bytes
+		[]byte("I vote for Smith"),           // free text
+	}
+	for i, bad := range malformed {
+		if _, err := schema.DecodeSelections(bad); !errors.Is(err, voting.ErrMalformedBallot) {
+			panic(fmt.Sprintf("Malformed plaintext %d returned %v", i, err))
+		}
+	}
+	if _, err := other.DecodeSelections(data); !errors.Is(err, voting.ErrMalformedBallot) {
+		panic(fmt.Sprintf("Selections of another schema returned %v", err))
+	}
+	tooMany := append([]byte{}, data...)
+	tooMany[5] = 2 // two candidates for a question that takes one
+	if _, err := schema.DecodeSelections(tooMany); !errors.Is(err, voting.ErrMalformedBallot) && !errors.Is(err, voting.ErrInvalidSelection) {
+		panic(fmt.Sprintf("Plaintext breaking the schema returned %v", err))
+	}
+}
+
+// preform a tally: mix and decrypt typed ballots, count them per question alongside invalid and spoiled ones,
+// and check the signed result document
+func doTallyTest(suite suites.Suite, listLength, contributorCount, threshold int) {
+
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+	publicKey := shares[0].Public()
+
+	schema := sanitySchema()
+	selections, messages, vectors, err := voting.GenerateSelectionVectors(suite, schema, listLength, publicKey)
+	check(err)
+	shuffled, _, err := voting.ShuffleVectorsAndCheck(suite, publicKey, vectors)
+	check(err)
+	decrypted, _, err := voting.DecryptVectors(suite, shuffled, shares, threshold, contributorCount)
+	check(err)
+
+	tally, err := voting.NewTally(suite, schema)
+	check(err)
+	if rejected := tally.AddVectors(decrypted); len(rejected) > 0 {
+		panic(fmt.Sprintf("Valid ballots were rejected: %v", rejected))
+	}
+	if err := tally.Add([]byte("I vote for Smith")); !errors.Is(err, voting.ErrMalformedBallot) {
+		panic(fmt.Sprintf("Free text ballot returned %v", err))
+	}
+	// a ballot missing a chunk is invalid, a whole one is counted
+	_, rejected, err := tally.AddPortions(append(messages[0][1:], messages[1]...), voting.DefaultReassemblyPolicy)
+	check(err)
+	if len(rejected) != 1 {
+		panic(fmt.Sprintf("%d ballots were rejected instead of 1", len(rejected)))
+	}
+	tally.AddSpoiled(1)
+
+	// the counts match the selections, ballot 1 being counted twice
+	expected := make([][]int, len(schema.Questions))
+	blank := make([]int, len(schema.Questions))
+	for q, question := range schema.Questions {
+		expected[q] = make([]int, len(question.Candidates))
+	}
+	for _, ballot := range append(selections, selections[1]) {
+		for q, selection := range ballot {
+			for _, candidate := range selection.Candidates {
+				expected[q][candidate]++
+			}
+			if len(selection.Candidates) == 0 && len(selection.WriteIns) == 0 {
+				blank[q]++
+			}
+		}
+	}
+
+	authority := suite.Scalar().Pick(suite.RandomStream())
+	result, err := tally.Result(authority)
+	check(err)
+	if result.Ballots != listLength+3 || result.Counted != listLength+1 || result.Invalid != 2 || result.Spoiled != 1 {
+		panic(fmt.Sprintf("Tally found %d ballots, %d counted, %d invalid and %d spoiled", result.Ballots, result.Counted, result.Invalid, result.Spoiled))
+	}
+	for q := range schema.Questions {
+		if fmt.Sprint(result.Questions[q].Counts) != fmt.Sprint(expected[q]) || result.Questions[q].Blank != blank[q] {
+			panic(fmt.Sprintf("Question %d was counted %v, expected %v", q, result.Questions[q].Counts, expected[q]))
+		}
+	}
+
+	// the result document can be published, and checked by anyone
+	data, err := json.Marshal(result)
+	check(err)
+	published := &voting.TallyResult{}
+	check(json.Unmarshal(data, published))
+	check(published.Verify(suite, schema))
+	if !published.Authority.Equal(suite.Point().Mul(authority, nil)) {
+		panic("The result wasn't signed by the authority")
+	}
+	published.Questions[0].Counts[0]++
+	if err := published.Verify(suite, schema); !errors.Is(err, voting.ErrInvalidResult) {
+		panic(fmt.Sprintf("Altered result returned %v", err))
+	}
+}
+
+// the ballot schema used by the self-tests
+func sanitySchema() *voting.BallotSchema {
+	return &voting.BallotSchema{
+		ElectionID: "sanity",
+		Questions: []*voting.BallotQuestion{
+			{ID: "mayor", Text: "Who should be mayor?", Candidates: []string{"Smith", "Jones", "Brown"}, MinSelections: 1, MaxSelections: 1},
+			{ID: "council", Text: "Who should sit on the council?", Candidates: []string{"Adams", "Baker", "Clark"}, MaxSelections: 3, WriteIns: 1},
+		},
+		WriteInLength: 32,
+	}
+}
+
+// preform a homomorphic tally: cast votes for candidates, sum them per candidate,
+// and threshold decrypt only the totals
+func doHomomorphicTest(suite suites.Suite, listLength, contributorCount, threshold int) {
+
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+	publicKey := shares[0].Public()
+
+	candidateCount := 3
+	expected := make([]int64, candidateCount)
+	ballots := make([]*voting.Ballot, 0, listLength)
+	for i := 0; i < listLength; i++ {
+		choice := i % candidateCount
+		ballot, err := voting.EncryptVote(suite, choice, candidateCount, publicKey, "sanity", fmt.Sprintf("voter%d", i))
+		check(err)
+		ballots = append(ballots, ballot)
+		expected[choice]++
+	}
+
+	accepted, rejected := voting.IngestVoteBallots(suite, "sanity", candidateCount, publicKey, ballots)
+	if len(rejected) > 0 {
+		panic(fmt.Sprintf("Valid votes were rejected: %v", rejected))
+	}
+	totals, err := voting.AggregateVotes(suite, accepted, candidateCount)
+	check(err)
+	counts, _, err := voting.DecryptTally(suite, totals, shares, threshold, contributorCount, int64(len(accepted)))
+	check(err)
+	for j := range counts {
+		if counts[j] != expected[j] {
+			panic(fmt.Sprintf("Candidate %d got %d votes instead of %d!", j, counts[j], expected[j]))
+		}
+	}
+}
+
+// preform a mixnet test: pass the ciphertexts through a cascade of mix servers,
+// verify the whole chain from its records, then decrypt the output
+func doMixnetTest(suite suites.Suite, listLength, serverCount int) {
+
+	a, h := sanityKeys(suite) // the private and public key
+
+	messages, elGamal1, elGamal2 := voting.GenerateMessageEncryptions(suite, listLength, h)
+	input, err := voting.JoinCiphertexts(elGamal1, elGamal2)
+	check(err)
+
+	mixers := make([]voting.Mixer, serverCount)
+	for i := range mixers {
+		mixers[i] = &voting.LocalMixer{ServerName: fmt.Sprintf("mix%d", i)}
+	}
+	output, records, err := voting.NewMixnet(suite, h, mixers...).Run(input)
+	check(err)
+
+	verifiedOutput, err := voting.VerifyMixChain(suite, h, input, records)
+	check(err)
+	if len(verifiedOutput) != len(output) {
+		panic("Verified mix chain doesn't end with the mixnet's output!")
+	}
+
+	// a chain missing a server doesn't link up
+	if _, err := voting.VerifyMixChain(suite, h, input, records[1:]); err == nil {
+		panic("Broken mix chain was verified!")
+	}
+
+	elGamal1, elGamal2, err = voting.SplitCiphertexts(output)
+	check(err)
+	decrypted, err := voting.DecryptAll(suite, elGamal1, elGamal2, a)
+	check(err)
+	check(voting.CheckDecryption(messages, decrypted))
+}
+
+// preform a whole election from its manifest: key ceremony, voting, mixing and decryption,
+// making sure the election refuses what its current phase doesn't allow
+func doElectionTest(suite suites.Suite, voterCount, contributorCount, threshold int) {
+
+	start := time.Now()
+	trustees := make([]string, contributorCount)
+	for i := range trustees {
+		trustees[i] = fmt.Sprintf("trustee%d", i)
+	}
+	manifest := &voting.Manifest{
+		ElectionID: "sanity",
+		Suite:      suite.String(),
+		Questions: []*voting.Question{
+			{ID: "mayor", Text: "Who should be mayor?", Candidates: []string{"Smith", "Queen", "Jones"}},
+			{ID: "park", Text: "Should the park be extended?", Candidates: []string{"Yes", "No"}},
+		},
+		Trustees:    trustees,
+		Threshold:   threshold,
+		VotingStart: start,
+		VotingEnd:   start.Add(time.Hour),
+	}
+	election, err := voting.NewElection(manifest)
+	check(err)
+	clock := start
+	election.Now = func() time.Time { return clock }
+
+	// every artifact of the election is posted to a bulletin board
+	dir, err := os.MkdirTemp("", "board")
+	check(err)
+	defer os.RemoveAll(dir)
+	boardPath := filepath.Join(dir, "board.jsonl")
+	bulletinBoard, err := board.Open(suite, boardPath)
+	check(err)
+	defer bulletinBoard.Close()
+	boardKey := suite.Scalar().Pick(suite.RandomStream())
+	check(election.UseBoard(bulletinBoard, boardKey))
+
+	// no ballot can be cast before the key ceremony is over
+	if _, err := election.Cast(&voting.Ballot{}); !errors.Is(err, voting.ErrWrongPhase) {
+		panic(fmt.Sprintf("Ballot cast during the key ceremony returned %v", err))
+	}
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+	check(election.CompleteKeyCeremony(shares[0].Public(), shares[0].Commitments()))
+
+	// only registered voters can vote, and a voter who votes again replaces their ballot
+	registry := voting.NewRegistry(suite, manifest.ElectionID, voting.LastVoteCounts)
+	voterKeys := make([]kyber.Scalar, voterCount)
+	for i := range voterKeys {
+		var public kyber.Point
+		voterKeys[i], public = voting.NewVoterKey(suite)
+		check(registry.Register(fmt.Sprintf("voter%d", i), public))
+	}
+	check(election.UseRegistry(registry))
+	check(election.OpenVoting())
+	if _, err := election.Candidates(2); err == nil {
+		panic("Found the candidates of a question that doesn't exist!")
+	}
+
+	expected := [][]int{make([]int, 3), make([]int, 2)}
+	var last *voting.Ballot
+	codes := make([]string, voterCount)
+	for i := 0; i < voterCount; i++ {
+		choices := []int{i % 3, i % 2}
+		last, err = election.EncryptBallot(choices, fmt.Sprintf("voter%d", i))
+		check(err)
+		check(last.Sign(suite, voterKeys[i]))
+		codes[i], err = election.Cast(last)
+		check(err)
+		expected[0][choices[0]]++
+		expected[1][choices[1]]++
+	}
+	if _, err := election.Cast(last); !errors.Is(err, voting.ErrDuplicateCiphertext) {
+		panic(fmt.Sprintf("Copied ballot returned %v", err))
+	}
+	unsigned, err := election.EncryptBallot([]int{0, 0}, "voter0")
+	check(err)
+	if _, err := election.Cast(unsigned); !errors.Is(err, voting.ErrInvalidBallotSignature) {
+		panic(fmt.Sprintf("Unsigned ballot returned %v", err))
+	}
+	stranger, err := election.EncryptBallot([]int{0, 0}, "stranger")
+	check(err)
+	check(stranger.Sign(suite, voterKeys[0]))
+	if _, err := election.Cast(stranger); !errors.Is(err, voting.ErrUnknownVoter) {
+		panic(fmt.Sprintf("Ballot of an unregistered voter returned %v", err))
+	}
+	revote, err := election.EncryptBallot([]int{2, 1}, "voter0")
+	check(err)
+	check(revote.Sign(suite, voterKeys[0]))
+	_, err = election.Cast(revote)
+	check(err)
+	expected[0][0]--
+	expected[1][0]--
+	expected[0][2]++
+	expected[1][1]++
+
+	// a voter audits their device: the spoiled ballot opens to their choices, and can't be cast anymore
+	pending, err := election.PrepareBallot([]int{1, 0}, "auditor")
+	check(err)
+	spoiled := pending.Spoil()
+	data, err := json.Marshal(spoiled)
+	check(err)
+	published := &voting.SpoiledBallot{}
+	check(json.Unmarshal(data, published))
+	names, err := election.Spoil(published)
+	check(err)
+	if names[0] != "Queen" || names[1] != "Yes" {
+		panic(fmt.Sprintf("Spoiled ballot opened to %v!", names))
+	}
+	if _, err := election.Cast(pending.Ballot); !errors.Is(err, voting.ErrSpoiledBallot) {
+		panic(fmt.Sprintf("Casting a spoiled ballot returned %v", err))
+	}
+	if _, err := election.Spoil(&voting.SpoiledBallot{}); !errors.Is(err, voting.ErrInvalidSpoiledBallot) {
+		panic(fmt.Sprintf("Spoiling without a ballot returned %v", err))
+	}
+	published.Randomness[0] = suite.Scalar().Pick(suite.RandomStream())
+	if err := published.Verify(suite, election.PublicKey()); !errors.Is(err, voting.ErrInvalidSpoiledBallot) {
+		panic(fmt.Sprintf("Spoiled ballot with the wrong randomness returned %v", err))
+	}
+
+	// voting can't close before the end of the window, and takes no ballot after it
+	if err := election.CloseVoting(); !errors.Is(err, voting.ErrOutsideVotingWindow) {
+		panic(fmt.Sprintf("Closing voting early returned %v", err))
+	}
+	clock = clock.Add(2 * time.Hour)
+	late, err := election.EncryptBallot([]int{0, 0}, "late")
+	check(err)
+	if _, err := election.Cast(late); !errors.Is(err, voting.ErrOutsideVotingWindow) {
+		panic(fmt.Sprintf("Late ballot returned %v", err))
+	}
+	check(election.CloseVoting())
+
+	// every voter finds their ballot from its tracking code, in the tree whose root was posted when voting closed
+	for i, code := range codes {
+		proof, root, err := election.Track(strings.ToLower(code))
+		check(err)
+		check(proof.Verify(root))
+		if proof.TrackingCode() != code || proof.Index != i {
+			panic(fmt.Sprintf("Tracking code %s found ballot %d!", code, proof.Index))
+		}
+	}
+	proof, root, err := election.Track(codes[0])
+	check(err)
+	proof.BallotHash = append([]byte(nil), proof.BallotHash...)
+	proof.BallotHash[0] ^= 1
+	if err := proof.Verify(root); !errors.Is(err, voting.ErrInvalidInclusionProof) {
+		panic(fmt.Sprintf("Inclusion proof of an altered ballot returned %v", err))
+	}
+	if _, _, err := election.Track("AAAA-AAAA-AAAA-AAAA-AAAA-AAAA"); !errors.Is(err, voting.ErrUnknownTrackingCode) {
+		panic(fmt.Sprintf("Unknown tracking code returned %v", err))
+	}
+
+	// the ballots must be mixed before they are decrypted
+	if _, err := election.Decrypt(shares); !errors.Is(err, voting.ErrWrongPhase) {
+		panic(fmt.Sprintf("Decrypting before mixing returned %v", err))
+	}
+	_, err = election.Mix(&voting.LocalMixer{ServerName: "mix0"}, &voting.LocalMixer{ServerName: "mix1"})
+	check(err)
+	misbehaving, err := election.Decrypt(shares)
+	check(err)
+	if len(misbehaving) > 0 {
+		panic(fmt.Sprintf("Honest trustees %v were reported as misbehaving", misbehaving))
+	}
+
+	counts, err := election.Results()
+	check(err)
+	for i := range expected {
+		for j := range expected[i] {
+			if counts[i][j] != expected[i][j] {
+				panic(fmt.Sprintf("Candidate %d of question %d got %d votes instead of %d!", j, i, counts[i][j], expected[i][j]))
+			}
+		}
+	}
+
+	// the board holds the manifest, the election key, the registry, the ballots and the replacing ballot, the spoiled ballot,
+	// the root of the tree of cast ballots, two mix records and a partial decryption from every trustee for each question, and the result
+	check(bulletinBoard.Verify())
+	if want := 1 + 1 + 1 + voterCount + 1 + 1 + 1 + 2*2 + 2*contributorCount + 1; bulletinBoard.Len() != want {
+		panic(fmt.Sprintf("Bulletin board holds %d entries instead of %d!", bulletinBoard.Len(), want))
+	}
+	ballotEntries, err := bulletinBoard.Read(3, 3+voterCount+1)
+	check(err)
+	for _, entry := range ballotEntries {
+		if entry.Kind != board.KindBallot {
+			panic(fmt.Sprintf("Entry %d is a %s, not a ballot!", entry.Index, entry.Kind))
+		}
+	}
+
+	// the board reads back from its file, and any change to an entry breaks the chain
+	entries, err := board.ReadEntries(boardPath)
+	check(err)
+	check(board.VerifyChain(suite, entries))
+	entries[1].Payload = entries[2].Payload
+	if err := board.VerifyChain(suite, entries); !errors.Is(err, board.ErrBrokenChain) {
+		panic(fmt.Sprintf("Tampered bulletin board returned %v", err))
+	}
+
+	// an auditor re-verifies the whole election from the board alone
+	entries, err = board.ReadEntries(boardPath)
+	check(err)
+	for _, c := range voting.AuditBoard(entries) {
+		if c.Err != nil {
+			panic(fmt.Sprintf("Audit check %s failed: %v", c.Name, c.Err))
+		}
+	}
+
+	// a board signed by the election, but with a result the ballots don't add up to, fails the audit
+	forgedPath := filepath.Join(dir, "forged.jsonl")
+	forged, err := board.Open(suite, forgedPath)
+	check(err)
+	defer forged.Close()
+	for _, entry := range entries {
+		var payload interface{} = entry.Payload
+		if entry.Kind == board.KindResult {
+			payload = &voting.PublishedResult{Counts: [][]int{{voterCount, 0, 0}, {voterCount, 0}}}
+		}
+		_, err := forged.Append(boardKey, entry.Kind, payload)
+		check(err)
+	}
+	forgedEntries, err := board.ReadEntries(forgedPath)
+	check(err)
+	failures := 0
+	for _, c := range voting.AuditBoard(forgedEntries) {
+		if c.Err != nil {
+			failures++
+		}
+	}
+	if failures != len(manifest.Questions) {
+		panic(fmt.Sprintf("Forged result failed %d audit checks instead of %d!", failures, len(manifest.Questions)))
+	}
+
+	// a partial decryption that doesn't verify is only warned about, as long as enough valid ones remain
+	faultyPath := filepath.Join(dir, "faulty.jsonl")
+	faulty, err := board.Open(suite, faultyPath)
+	check(err)
+	defer faulty.Close()
+	moved := false
+	for _, entry := range entries {
+		var payload interface{} = entry.Payload
+		if entry.Kind == board.KindPartialDecryption && !moved {
+			partial := &voting.PublishedPartialDecryption{}
+			check(json.Unmarshal(entry.Payload, partial))
+			partial.Question = manifest.Questions[1].ID // the partial of another question, which can't verify
+			payload, moved = partial, true
+		}
+		_, err := faulty.Append(boardKey, entry.Kind, payload)
+		check(err)
+	}
+	faultyEntries, err := board.ReadEntries(faultyPath)
+	check(err)
+	warned := false
+	for _, c := range voting.AuditBoard(faultyEntries) {
+		if c.Err != nil {
+			panic(fmt.Sprintf("Audit check %s failed with a single faulty trustee: %v", c.Name, c.Err))
+		}
+		warned = warned || strings.Contains(c.Detail, "warning")
+	}
+	if !warned {
+		panic("The faulty trustee wasn't warned about!")
+	}
+}
+
+// run an election where a single ballot is cast: it can't be shuffled, so it is counted unmixed,
+// and the audit accepts the question without mix records
+func doSingleBallotTest(suite suites.Suite, contributorCount, threshold int) {
+
+	start := time.Now()
+	trustees := make([]string, contributorCount)
+	for i := range trustees {
+		trustees[i] = fmt.Sprintf("trustee%d", i)
+	}
+	manifest := &voting.Manifest{
+		ElectionID:  "sanity",
+		Suite:       suite.String(),
+		Questions:   []*voting.Question{{ID: "park", Text: "Should the park be extended?", Candidates: []string{"Yes", "No"}}},
+		Trustees:    trustees,
+		Threshold:   threshold,
+		VotingStart: start,
+		VotingEnd:   start.Add(time.Hour),
+	}
+	election, err := voting.NewElection(manifest)
+	check(err)
+	clock := start
+	election.Now = func() time.Time { return clock }
+
+	dir, err := os.MkdirTemp("", "board")
+	check(err)
+	defer os.RemoveAll(dir)
+	boardPath := filepath.Join(dir, "board.jsonl")
+	bulletinBoard, err := board.Open(suite, boardPath)
+	check(err)
+	defer bulletinBoard.Close()
+	check(election.UseBoard(bulletinBoard, suite.Scalar().Pick(suite.RandomStream())))
+
+	shares, err := voting.CreateThresholdShares(suite, contributorCount, threshold)
+	check(err)
+	check(election.CompleteKeyCeremony(shares[0].Public(), shares[0].Commitments()))
+	check(election.OpenVoting())
+	ballot, err := election.EncryptBallot([]int{1}, "voter")
+	check(err)
+	_, err = election.Cast(ballot)
+	check(err)
+	clock = start.Add(time.Hour)
+	check(election.CloseVoting())
+
+	records, err := election.Mix(&voting.LocalMixer{ServerName: "mix0"})
+	check(err)
+	if len(records[0]) != 0 {
+		panic(fmt.Sprintf("A single ballot went through %d mix servers!", len(records[0])))
+	}
+	_, err = election.Decrypt(shares)
+	check(err)
+	counts, err := election.Results()
+	check(err)
+	if counts[0][0] != 0 || counts[0][1] != 1 {
+		panic(fmt.Sprintf("A single ballot for No counted as %v!", counts[0]))
+	}
+
+	entries, err := board.ReadEntries(boardPath)
+	check(err)
+	for _, c := range voting.AuditBoard(entries) {
+		if c.Err != nil {
+			panic(fmt.Sprintf("Audit check %s failed: %v", c.Name, c.Err))
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/SpencerBouck/crypto-voting/board"
+	"github.com/SpencerBouck/crypto-voting/ceremony"
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// runs the coordinator of a key ceremony
+// the trustees connect to it with the trustee command
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8080", "the address to listen on")
+	n := flag.Int("n", 5, "the number of trustees")
+	t := flag.Int("t", 3, "the threshold")
+	suiteName := flag.String("suite", voting.DefaultSuite, "the cipher suite of the election")
+	boardPath := flag.String("board", "", "the bulletin board file to post the ceremony messages to, if any")
+	flag.Parse()
+
+	suite, err := voting.FindSuite(*suiteName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	coordinator := ceremony.NewCoordinator(suite, *n, *t)
+
+	var bulletinBoard *board.Board
+	if *boardPath != "" {
+		if bulletinBoard, err = board.Open(suite, *boardPath); err != nil {
+			log.Fatal(err)
+		}
+		// the entries are signed with a key of this run, which is logged so auditors can tell the coordinator's entries apart
+		key := suite.Scalar().Pick(suite.RandomStream())
+		coordinator.UseBoard(bulletinBoard, key)
+		log.Printf("Posting to %s as %s", *boardPath, suite.Point().Mul(key, nil))
+	}
+
+	log.Printf("Coordinating a %s ceremony between %d trustees with threshold %d on %s", suite, *n, *t, *addr)
+	// serve until interrupted, then let the posts in flight finish before the board is closed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Addr: *addr, Handler: coordinator}
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutting down: %v", err)
+		}
+	}()
+	err = server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdown // ListenAndServe returns as soon as the shutdown starts, not once it is done
+		err = nil
+	}
+
+	// log.Fatal skips deferred calls, so the board is closed here on every path
+	if bulletinBoard != nil {
+		if closeErr := bulletinBoard.Close(); closeErr != nil {
+			log.Printf("Closing %s: %v", *boardPath, closeErr)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Stopped")
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go.dedis.ch/kyber/v3"
+
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// helper function to quickly make sure no error exists
+func check(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+	suiteName := flag.String("suite", voting.DefaultSuite, "the cipher suite to run the test on")
+	flag.Parse()
+	suite, err := voting.FindSuite(*suiteName)
+	check(err)
+
+	// the filepath
+	filepath := "longMessageTestData.txt"
+
+	// open a file for recording the test data
+	file, err := os.Create(filepath)
+	check(err)         // make sure nothing's wrong
+	defer file.Close() // close the file eventually
+
+	// the questions on every ballot
+	schema := &voting.BallotSchema{
+		ElectionID: "longmessage",
+		Questions: []*voting.BallotQuestion{
+			{ID: "mayor", Text: "Who should be mayor?", Candidates: []string{"Smith", "QUEEN", "NIL"}, MinSelections: 1, MaxSelections: 1},
+			{ID: "park", Text: "Should the park be extended?", Candidates: []string{"Yes", "No"}, MaxSelections: 1},
+			{ID: "council", Text: "Who should sit on the council?", Candidates: []string{"BOB", "ALBERT", "ALSO BOB"}, MaxSelections: 2, WriteIns: 1},
+		},
+		WriteInLength: 24,
+	}
+	check(schema.Validate())
+	authority := suite.Scalar().Pick(suite.RandomStream()) // the key the tally is signed with
+
+	n := 5 // the number of contributors in the scheme
+	t := 5 // the threshold
+
+	// record the parameters of the test
+	_, err = file.WriteString("Environment Creation:\n")
+	check(err)
+	_, err = file.WriteString("Using " + strconv.Itoa(n) + " cotruibutors with threshold " + strconv.Itoa(t) + "\n\n")
+	check(err)
+
+	start := time.Now() // start timer
+
+	// create the environment for the tests
+	shares, err := voting.CreateThresholdShares(suite, n, t) // this is where the bulk of the time is spent: overhead for creating the system
+	check(err)
+	// each user's share will contain the public key
+	// Since these are all the same, we choose to use the copy at index 0 arbitrarlily
+	publicKey := shares[0].Public()
+
+	elapsed := time.Since(start)                        // end timer
+	log.Printf("Environment Creation took %s", elapsed) // log the time
+	_, err = file.WriteString(elapsed.String() + "\n")  // record the time in file
+	check(err)
+
+	// check each with an exponentially increasing number of ballots
+	for ballotCount := 50; ballotCount < 100; ballotCount *= 2 {
+
+		// state the number of ballots encrypted
+		_, err = file.WriteString("\nEncrypting " + strconv.Itoa(ballotCount) + " ballots\n")
+		check(err)
+
+		for i := 0; i < 1; i++ { // do 1 tests
+
+			start := time.Now() // start timer
+			// doThresholdTest(ballotCount, n, t) // do test
+
+			// generate ballots of the schema, each encrypted as a vector of chunks
+			_, messages, vectors, err := voting.GenerateSelectionVectors(suite, schema, ballotCount, publicKey)
+			check(err)
+
+			elapsed := time.Since(start)                       // end timer
+			log.Printf("Encryption took %s", elapsed)          // log the time
+			_, err = file.WriteString(elapsed.String() + "\n") // record the time in file
+			check(err)
+			start = time.Now() // restart timer
+
+			// shuffle the messages, the chunks of each message staying together
+			vectors, _, err = voting.ShuffleVectorsAndCheck(suite, publicKey, vectors)
+			check(err)
+
+			elapsed = time.Since(start)                        // end timer
+			log.Printf("Shuffle took %s", elapsed)             // log the time
+			_, err = file.WriteString(elapsed.String() + "\n") // record the time in file
+			check(err)
+			start = time.Now() // restart timer
+
+			// decrypt the messages, using the distributed shares
+			decryptedVectors, misbehaving, err := voting.DecryptVectors(suite, vectors, shares, t, n)
+			check(err)
+			if len(misbehaving) > 0 {
+				log.Printf("Trustees %v gave invalid shadows", misbehaving)
+			}
+
+			fmt.Println("Decoded Ballots:")
+			var originalPortions, decryptedPortions []kyber.Point
+			for k, portions := range decryptedVectors {
+				selections, err := schema.DecodeVector(portions)
+				check(err)
+				fmt.Println(schema.Format(selections))
+				originalPortions = append(originalPortions, messages[k]...)
+				decryptedPortions = append(decryptedPortions, portions...)
+			}
+
+			// assures all decryptions are correct
+			check(voting.CheckDecryption(originalPortions, decryptedPortions))
+
+			// count the ballots per question, and sign the result
+			tally, err := voting.NewTally(suite, schema)
+			check(err)
+			if rejected := tally.AddVectors(decryptedVectors); len(rejected) > 0 {
+				log.Printf("Ballots %v were invalid", rejected)
+			}
+			result, err := tally.Result(authority)
+			check(err)
+			check(result.Verify(suite, schema))
+			fmt.Printf("Tally: %d ballots, %d counted, %d invalid\n", result.Ballots, result.Counted, result.Invalid)
+			for q, question := range schema.Questions {
+				fmt.Printf("%s: %v, write-ins %v, blank %d\n", question.ID, result.Questions[q].Counts, result.Questions[q].WriteIns, result.Questions[q].Blank)
+			}
+
+			elapsed = time.Since(start)                        // end timer
+			log.Printf("Decryption took %s", elapsed)          // log the time
+			_, err = file.WriteString(elapsed.String() + "\n") // record the time in file
+			check(err)
+		}
+	}
+}
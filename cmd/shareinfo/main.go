@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// prints the public parts of a stored share
+// no password is needed, as the private share stays encrypted
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shareinfo <share file>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	stored, err := voting.ReadStoredShare(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	suite, err := stored.CipherSuite()
+	if err != nil {
+		log.Fatal(err)
+	}
+	publicKey, err := stored.Public(suite)
+	if err != nil {
+		log.Fatal(err)
+	}
+	commits, err := stored.Commits(suite)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("Format version:", stored.Version)
+	fmt.Println("Suite:", stored.Suite)
+	fmt.Println("Trustee index:", stored.Index)
+	fmt.Println("Public key:", publicKey)
+	fmt.Println("Verification key:", voting.VerificationKey(suite, commits, stored.Index))
+	fmt.Println("Commitments:")
+	for i, commit := range commits {
+		fmt.Printf("  %d: %s\n", i, commit)
+	}
+	fmt.Printf("Private share: encrypted with %s (%d iterations)\n", stored.KDF, stored.Iterations)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/SpencerBouck/crypto-voting/ceremony"
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// runs a trustee through a key ceremony
+// the trustee's long-term secret and share never leave this process,
+// except for the share, which is saved encrypted with the trustee's password
+func main() {
+	coordinator := flag.String("coordinator", "http://127.0.0.1:8080", "the URL of the coordinator")
+	timeout := flag.Duration("timeout", 10*time.Minute, "how long to wait for the other trustees")
+	out := flag.String("out", "share.json", "the file to save the share to")
+	passwordFile := flag.String("password-file", "", "the file holding the password protecting the share")
+	suiteName := flag.String("suite", voting.DefaultSuite, "the cipher suite of the election, must match the coordinator's")
+	flag.Parse()
+
+	suite, err := voting.FindSuite(*suiteName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *passwordFile == "" {
+		log.Fatal("a password file is needed to protect the share")
+	}
+	password, err := os.ReadFile(*passwordFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	password = bytes.TrimRight(password, "\r\n") // ignore the trailing newline left by editors
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	node := ceremony.NewNode(suite, *coordinator)
+	distShare, err := node.Run(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Trustee %d finished the ceremony", node.Index())
+	log.Printf("Election public key: %s", distShare.Public())
+
+	if err := voting.SaveShare(suite, *out, distShare, password); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Share saved to %s", *out)
+}
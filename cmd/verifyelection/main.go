@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/SpencerBouck/crypto-voting/board"
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// verifies a whole election from its bulletin board, offline
+// only what the election published is used: the manifest, the election key, the ballots and their proofs,
+// the spoiled ballots, the mix records, the partial decryptions and the result
+// every check is printed as PASS or FAIL, and the exit status is 1 if any of them failed
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: verifyelection <board file>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entries, err := board.ReadEntries(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	failed := false
+	for _, check := range voting.AuditBoard(entries) {
+		if check.Err != nil {
+			fmt.Printf("FAIL %s: %v\n", check.Name, check.Err)
+			failed = true
+			continue
+		}
+		fmt.Printf("PASS %s: %s\n", check.Name, check.Detail)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/SpencerBouck/crypto-voting/voting"
+)
+
+// checks published shuffle records, offline
+// every record is checked on its own, and when several are given, in the order of the cascade,
+// that each one starts from the output of the previous one, under the same public key
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: verifyshuffle <record file>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	records := make([]*voting.MixRecord, flag.NArg())
+	failed := false
+	for i, path := range flag.Args() {
+		// a record that can't be loaded fails like any other, the records may come from anyone
+		record, err := voting.LoadMixRecord(path)
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		records[i] = record
+
+		// loading the record already made sure its suite is supported
+		suite, err := voting.FindSuite(record.Suite)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := record.Verify(suite); err != nil {
+			fmt.Printf("FAIL %s (%s): %v\n", path, record.Server, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("PASS %s (%s): %d ciphertexts shuffled\n", path, record.Server, len(record.Output))
+	}
+
+	if len(records) > 1 && records[0] != nil {
+		suite, err := voting.FindSuite(records[0].Suite)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := voting.VerifyMixChain(suite, records[0].PublicKey, records[0].Input, records); err != nil {
+			fmt.Println("FAIL chain:", err)
+			failed = true
+		} else {
+			fmt.Printf("PASS chain: %d records link up\n", len(records))
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/SpencerBouck/crypto-voting
+
+go 1.24
+
+require go.dedis.ch/kyber/v3 v3.1.0
+
+require (
+	go.dedis.ch/fixbuf v1.0.3 // indirect
+	go.dedis.ch/protobuf v1.0.11 // indirect
+	golang.org/x/crypto v0.0.0-20190123085648-057139ce5d2b // indirect
+	golang.org/x/sys v0.0.0-20190124100055-b90733256f2e // indirect
+)
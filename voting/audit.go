@@ -0,0 +1,325 @@
+package voting
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/kyber/v3"
+
+	"github.com/SpencerBouck/crypto-voting/board"
+)
+
+// Check is the outcome of one step of an audit
+type Check struct {
+	Name   string // what was checked
+	Detail string // what was found, when the check passed
+	Err    error  // why the check failed, nil if it passed
+}
+
+// ElectionRecord is everything an election posted to its bulletin board, decoded
+type ElectionRecord struct {
+	Manifest    *Manifest                     // the manifest of the election
+	Key         *PublishedKey                 // the outcome of the key ceremony
+	Registry    *Registry                     // the voters of the election, if it checked eligibility
+	Ballots     []*Ballot                     // the cast ballots, in order
+	Spoiled     []*SpoiledBallot              // the spoiled ballots, in order
+	BallotRoot  *PublishedBallotRoot          // the root of the tree of cast ballots
+	MixRecords  []*PublishedMixRecord         // the records of the mix servers, in order
+	Decryptions []*PublishedPartialDecryption // the partial decryptions of the trustees
+	Result      *PublishedResult              // the counts of the election
+}
+
+// AuditBoard re-verifies a whole election from the entries of its bulletin board alone, and reports on every step:
+// the chain of the board, the manifest and election key, every ballot and its proofs, the eligibility of the voters, every spoiled ballot,
+// the root of the ballot tree, and for each question the mix chain, the partial decryptions and the counts
+// nothing but the published entries is needed, so anyone can run this
+func AuditBoard(entries []*board.Entry) (checks []*Check) {
+	report := func(name, detail string, err error) bool {
+		checks = append(checks, &Check{Name: name, Detail: detail, Err: err})
+		return err == nil
+	}
+
+	// the manifest comes first, and names the suite everything else is checked with
+	if len(entries) == 0 || entries[0].Kind != board.KindManifest {
+		report("manifest", "", errors.New("the board doesn't start with a manifest"))
+		return
+	}
+	manifest := &Manifest{}
+	err := json.Unmarshal(entries[0].Payload, manifest)
+	if err == nil {
+		err = manifest.Validate()
+	}
+	if !report("manifest", "", err) {
+		return
+	}
+	suite, err := FindSuite(manifest.Suite)
+	candidates := make([][]kyber.Point, len(manifest.Questions))
+	for i := 0; err == nil && i < len(manifest.Questions); i++ {
+		candidates[i], err = EncodeCandidates(suite, manifest.Questions[i].Candidates)
+	}
+	checks[0].Detail = fmt.Sprintf("election %q on %s, %d questions, %d of %d trustees to decrypt",
+		manifest.ElectionID, manifest.Suite, len(manifest.Questions), manifest.Threshold, len(manifest.Trustees))
+	if err != nil {
+		checks[0].Err = fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+		return
+	}
+
+	if !report("bulletin board chain", fmt.Sprintf("%d entries, all linked and signed", len(entries)), board.VerifyChain(suite, entries)) {
+		return
+	}
+	record, err := decodeRecord(manifest, entries)
+	if !report("board entries", "every entry decoded, and signed by the election", err) {
+		return
+	}
+
+	// the election key
+	key := record.Key
+	if key == nil {
+		err = errors.New("no election key was posted")
+	} else if err = checkSuite(suite, key.Suite, "election key"); err == nil {
+		if len(key.Commitments) != manifest.Threshold {
+			err = fmt.Errorf("%d commitments posted, threshold is %d", len(key.Commitments), manifest.Threshold)
+		} else if !key.Commitments[0].Equal(key.PublicKey) {
+			err = errors.New("the public key doesn't match the commitments")
+		}
+	}
+	if !report("election key", "the public key matches the commitments", err) {
+		return
+	}
+
+	// every cast ballot, and every spoiled ballot
+	seen := make(map[string]bool)
+	err = nil
+	for i, ballot := range record.Ballots {
+		if err = verifyElectionBallot(suite, manifest, candidates, key.PublicKey, ballot); err == nil {
+			var keys map[string]bool
+			if keys, err = freshCiphertexts(seen, ballot); err == nil {
+				for k := range keys {
+					seen[k] = true
+				}
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("ballot %d: %w", i, err)
+			break
+		}
+	}
+	// a ballot that fails its checks may not even answer every question, so the questions can't be audited without them
+	ballotsValid := report("cast ballots", fmt.Sprintf("%d ballots, with valid proofs and no copies", len(record.Ballots)), err)
+
+	// with a registry, every ballot is signed by a registered voter, and the policy picks the ballots that count
+	counted := record.Ballots
+	if registry := record.Registry; registry != nil {
+		err = checkSuite(suite, registry.Suite, "voter registry")
+		if err == nil && registry.ElectionID != manifest.ElectionID {
+			err = fmt.Errorf("the registry is for election %q", registry.ElectionID)
+		}
+		voted := make(map[string]bool)
+		for i := 0; err == nil && i < len(record.Ballots); i++ {
+			ballot := record.Ballots[i]
+			if err = registry.VerifyBallot(suite, ballot); err == nil && voted[ballot.VoterID] && registry.Policy == FirstVoteCounts {
+				err = fmt.Errorf("%w: voter %q", ErrAlreadyVoted, ballot.VoterID)
+			}
+			if err != nil {
+				err = fmt.Errorf("ballot %d: %w", i, err)
+			}
+			voted[ballot.VoterID] = true
+		}
+		if err == nil {
+			counted = countedBallots(registry, record.Ballots)
+		}
+		report("voter eligibility", fmt.Sprintf("%d of %d registered voters, %d ballots counted under %s",
+			len(voted), registry.Len(), len(counted), registry.Policy), err)
+	}
+
+	err = nil
+	for i, spoiled := range record.Spoiled {
+		if err = spoiled.Ballot.Verify(suite, manifest.ElectionID); err == nil {
+			if err = spoiled.Verify(suite, key.PublicKey); err == nil {
+				if _, err = spoiledAnswers(manifest, candidates, spoiled); err == nil {
+					// a spoiled ballot must never be counted
+					_, err = freshCiphertexts(seen, spoiled.Ballot)
+				}
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("spoiled ballot %d: %w", i, err)
+			break
+		}
+	}
+	report("spoiled ballots", fmt.Sprintf("%d ballots, opening to their ciphertexts and left out of the tally", len(record.Spoiled)), err)
+
+	// the root voters check their tracking codes against
+	tree, err := NewBallotTree(record.Ballots)
+	if err == nil {
+		if record.BallotRoot == nil {
+			err = errors.New("no ballot root was posted")
+		} else if record.BallotRoot.Size != tree.Len() || !bytes.Equal(record.BallotRoot.Root, tree.Root()) {
+			err = fmt.Errorf("the posted root covers %d ballots, and doesn't match the %d cast", record.BallotRoot.Size, tree.Len())
+		}
+	}
+	report("ballot tree root", fmt.Sprintf("%x", tree.Root()), err)
+
+	// each question is mixed, decrypted and counted on its own
+	if !ballotsValid {
+		return
+	}
+	if record.Result == nil || len(record.Result.Counts) != len(manifest.Questions) {
+		report("result", "", errors.New("no result was posted for every question"))
+		return
+	}
+	for i, question := range manifest.Questions {
+		input := make([]*Ciphertext, len(counted))
+		for j, ballot := range counted {
+			input[j] = ballot.Ciphertexts[i]
+		}
+		var records []*MixRecord
+		for _, published := range record.MixRecords {
+			if published.Question == question.ID {
+				records = append(records, published.Record)
+			}
+		}
+		var partials []*PartialDecryption
+		for _, published := range record.Decryptions {
+			if published.Question == question.ID {
+				partials = append(partials, published.Decryption)
+			}
+		}
+		auditQuestion(report, question, candidates[i], key, manifest, input, records, partials, record.Result.Counts[i])
+	}
+	return // checks
+}
+
+// helper function, checks the mix chain, the partial decryptions and the counts of a question
+func auditQuestion(report func(name, detail string, err error) bool, question *Question, candidates []kyber.Point, key *PublishedKey, manifest *Manifest, input []*Ciphertext, records []*MixRecord, partials []*PartialDecryption, posted []int) {
+	suite, err := FindSuite(key.Suite)
+	if err != nil {
+		report(fmt.Sprintf("question %q", question.ID), "", err)
+		return
+	}
+
+	// mixing
+	output := input
+	if len(input) >= minShuffleLength {
+		if len(records) == 0 {
+			err = errors.New("the ballots were never mixed")
+		} else {
+			output, err = VerifyMixChain(suite, key.PublicKey, input, records)
+		}
+	} else if len(records) > 0 {
+		err = fmt.Errorf("%d ballots can't be mixed, yet %d mix records were posted", len(input), len(records))
+	}
+	if !report(fmt.Sprintf("mix chain of question %q", question.ID), fmt.Sprintf("%d ballots through %d servers", len(input), len(records)), err) {
+		return
+	}
+
+	// the shadows of every trustee
+	// robust decryption tolerates faulty trustees, so their partials are only warned about, and left out of the count
+	valid := make(map[int]bool)
+	var validPartials []*PartialDecryption
+	var faulty []string
+	for _, partial := range partials {
+		if err := partial.Verify(suite, output, key.Commitments); err != nil {
+			faulty = append(faulty, fmt.Sprintf("trustee %d: %v", partial.Trustee, err))
+			continue
+		}
+		if !valid[partial.Trustee] {
+			valid[partial.Trustee] = true
+			validPartials = append(validPartials, partial)
+		}
+	}
+	err = nil
+	if len(output) > 0 && len(valid) < manifest.Threshold {
+		err = fmt.Errorf("%w: %d trustees decrypted validly, threshold is %d", ErrInsufficientShadows, len(valid), manifest.Threshold)
+	}
+	detail := fmt.Sprintf("%d trustees, with valid proofs", len(valid))
+	if len(faulty) > 0 {
+		detail += fmt.Sprintf("; warning, ignored %d invalid partials (%s)", len(faulty), strings.Join(faulty, "; "))
+	}
+	report(fmt.Sprintf("partial decryptions of question %q", question.ID), detail, err)
+
+	// the counts, from the valid partials only
+	counts := make([]int, len(candidates))
+	if len(output) > 0 {
+		var decrypted []kyber.Point
+		decrypted, _, err = DecryptFromPartials(suite, output, validPartials, key.Commitments, manifest.Threshold, len(manifest.Trustees))
+		if err == nil {
+			counts, err = countAnswers(candidates, decrypted)
+		}
+	}
+	if err == nil && fmt.Sprint(counts) != fmt.Sprint(posted) {
+		err = fmt.Errorf("the ballots count to %v, the posted result is %v", counts, posted)
+	}
+	report(fmt.Sprintf("tally of question %q", question.ID), fmt.Sprintf("%v", counts), err)
+}
+
+// helper function, decodes the entries of an election's board
+// the entries of the key ceremony are left out, every other entry must be signed by the author of the manifest
+func decodeRecord(manifest *Manifest, entries []*board.Entry) (record *ElectionRecord, err error) {
+	record = &ElectionRecord{Manifest: manifest}
+	author := entries[0].Author
+	for _, entry := range entries[1:] {
+		switch entry.Kind {
+		case board.KindDeal, board.KindResponse, board.KindJustification, board.KindCeremonyResult:
+			continue // the trustees post these through the coordinator, and the key entry sums them up
+		}
+		if !bytes.Equal(entry.Author, author) {
+			return nil, fmt.Errorf("entry %d was signed by someone else than the election", entry.Index)
+		}
+		var target interface{}
+		switch entry.Kind {
+		case board.KindKey:
+			record.Key = &PublishedKey{}
+			target = record.Key
+		case board.KindRegistry:
+			if record.Registry != nil || len(record.Ballots) > 0 {
+				return nil, fmt.Errorf("entry %d: the voter registry must be posted once, before any ballot", entry.Index)
+			}
+			record.Registry = &Registry{}
+			target = record.Registry
+		case board.KindBallot:
+			ballot := &Ballot{}
+			record.Ballots = append(record.Ballots, ballot)
+			target = ballot
+		case board.KindSpoiledBallot:
+			spoiled := &SpoiledBallot{}
+			record.Spoiled = append(record.Spoiled, spoiled)
+			target = spoiled
+		case board.KindBallotRoot:
+			record.BallotRoot = &PublishedBallotRoot{}
+			target = record.BallotRoot
+		case board.KindMixRecord:
+			published := &PublishedMixRecord{}
+			record.MixRecords = append(record.MixRecords, published)
+			target = published
+		case board.KindPartialDecryption:
+			published := &PublishedPartialDecryption{}
+			record.Decryptions = append(record.Decryptions, published)
+			target = published
+		case board.KindResult:
+			record.Result = &PublishedResult{}
+			target = record.Result
+		default:
+			return nil, fmt.Errorf("entry %d is a %s, which an election doesn't post", entry.Index, entry.Kind)
+		}
+		if err := json.Unmarshal(entry.Payload, target); err != nil {
+			return nil, fmt.Errorf("entry %d (%s): %v", entry.Index, entry.Kind, err)
+		}
+	}
+
+	// the other entries hold pointers that decoding fills in, check that none were posted empty
+	for _, published := range record.MixRecords {
+		if published.Record == nil {
+			return nil, errors.New("a mix record entry holds no record")
+		}
+	}
+	for _, published := range record.Decryptions {
+		if published.Decryption == nil {
+			return nil, errors.New("a partial decryption entry holds no decryption")
+		}
+	}
+	return // record, nil
+}
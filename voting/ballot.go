@@ -0,0 +1,370 @@
+package voting
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the version of the binary encoding of ballots, bumped with every change of the layout:
+// 1 held the ciphertexts, 2 added the proofs of encryption, 3 the choice proofs,
+// 4 the sum proof, and 5 the voter's signature
+// only the current layout is read: a ballot is hashed in its own encoding,
+// so an older ballot can't be read back and re-encoded without changing its hash and tracking code
+const ballotEncodingVersion = 5
+
+// Ballot is an encrypted ballot, as it is cast and published
+// a short ballot holds a single ciphertext, a long ballot holds one for each of its message portions
+// the suite and election are part of the ballot, so it can't be replayed in another election
+// each ciphertext comes with a proof that the voter knows its randomness, bound to the voter and election
+// in candidate-list elections, each ciphertext also comes with a proof that it encrypts one of the candidates
+// in homomorphic elections, the ballot also proves that its ciphertexts add up to a single vote
+// in elections with a voter registry, the voter signs the ballot with their own key (see Sign)
+type Ballot struct {
+	Suite        string             // the name of the cipher suite the ciphertexts belong to
+	ElectionID   string             // the election the ballot was cast in
+	VoterID      string             // the voter who cast the ballot
+	Ciphertexts  []*Ciphertext      // the encrypted message portions
+	Proofs       []*EncryptionProof // the proofs of encryption, one for each ciphertext
+	ChoiceProofs []*ChoiceProof     `json:",omitempty"` // the proofs of valid choices, one for each ciphertext of a candidate-list ballot
+	SumProof     *ChoiceProof       `json:",omitempty"` // the proof that a homomorphic ballot selects exactly one candidate
+	Signature    []byte             `json:",omitempty"` // the voter's Schnorr signature over everything above
+}
+
+// NewBallot creates a ballot for the given election, holding the given ciphertexts of the suite
+// the ballot has no voter and no proofs, see EncryptBallot for ballots that can be cast
+func NewBallot(suite suites.Suite, electionID string, ciphertexts ...*Ciphertext) *Ballot {
+	return &Ballot{Suite: suite.String(), ElectionID: electionID, Ciphertexts: ciphertexts}
+}
+
+// EncryptBallot encrypts the message portions of a ballot with the public key,
+// and proves knowledge of the randomness of each, for the given election and voter
+func EncryptBallot(suite suites.Suite, messages []kyber.Point, pubKey kyber.Point, electionID, voterID string) *Ballot {
+	return EncryptPendingBallot(suite, messages, pubKey, electionID, voterID).Cast()
+}
+
+// EncryptChoiceBallot encrypts the choices of a voter in a candidate-list election
+// each choice is the index of a candidate, and is encrypted in its own ciphertext
+// along with its proof of encryption, each ciphertext gets a proof that it encrypts one of the candidates
+func EncryptChoiceBallot(suite suites.Suite, choices []int, candidates []kyber.Point, pubKey kyber.Point, electionID, voterID string) (*Ballot, error) {
+	ballot := &Ballot{
+		Suite:        suite.String(),
+		ElectionID:   electionID,
+		VoterID:      voterID,
+		Ciphertexts:  make([]*Ciphertext, len(choices)),
+		Proofs:       make([]*EncryptionProof, len(choices)),
+		ChoiceProofs: make([]*ChoiceProof, len(choices)),
+	}
+	for i, choice := range choices {
+		if choice < 0 || choice >= len(candidates) {
+			return nil, fmt.Errorf("choice %d is not one of the %d candidates", choice, len(candidates))
+		}
+		elGamal1, elGamal2, randomness := EncryptMessageWithRandomness(suite, candidates[choice], pubKey)
+		ballot.Ciphertexts[i] = &Ciphertext{C1: elGamal1, C2: elGamal2}
+		ballot.Proofs[i] = ProveEncryption(suite, ballot.Ciphertexts[i], randomness, electionID, voterID)
+		proof, err := ProveChoice(suite, ballot.Ciphertexts[i], randomness, choice, candidates, pubKey, electionID, voterID)
+		if err != nil {
+			return nil, err
+		}
+		ballot.ChoiceProofs[i] = proof
+	}
+	return ballot, nil
+}
+
+// VerifyChoices checks that every ciphertext of the ballot encrypts one of the candidates under pubKey
+// this is on top of Verify, for candidate-list elections
+func (b *Ballot) VerifyChoices(suite suites.Suite, candidates []kyber.Point, pubKey kyber.Point) error {
+	if len(b.ChoiceProofs) != len(b.Ciphertexts) {
+		return fmt.Errorf("%w: %d proofs for %d ciphertexts", ErrInvalidChoiceProof, len(b.ChoiceProofs), len(b.Ciphertexts))
+	}
+	for i, ciphertext := range b.Ciphertexts {
+		if err := VerifyChoice(suite, ciphertext, b.ChoiceProofs[i], candidates, pubKey, b.ElectionID, b.VoterID); err != nil {
+			return fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Verify checks that the ballot was cast in the given election, with the election's suite, and that all of its proofs hold
+func (b *Ballot) Verify(suite suites.Suite, electionID string) error {
+	if err := checkSuite(suite, b.Suite, "ballot"); err != nil {
+		return err
+	}
+	if b.ElectionID != electionID {
+		return fmt.Errorf("ballot was cast in election %q, not %q", b.ElectionID, electionID)
+	}
+	if len(b.Ciphertexts) == 0 {
+		return errors.New("ballot holds no ciphertexts")
+	}
+	if len(b.Proofs) != len(b.Ciphertexts) {
+		return fmt.Errorf("%w: %d proofs for %d ciphertexts", ErrInvalidEncryptionProof, len(b.Proofs), len(b.Ciphertexts))
+	}
+	for i, ciphertext := range b.Ciphertexts {
+		if err := VerifyEncryption(suite, ciphertext, b.Proofs[i], b.ElectionID, b.VoterID); err != nil {
+			return fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// MarshalBinary encodes the ballot canonically:
+// the encoding version (1 byte),
+// the suite name, the election ID and the voter ID (each a 2 byte length followed by the string),
+// the number of ciphertexts (4 bytes) and each ciphertext (C1 followed by C2),
+// the number of proofs (4 bytes) and each proof (challenge followed by response),
+// the number of choice proofs (4 bytes) and each choice proof (its 4 byte length followed by its encoding),
+// the sum proof (its 4 byte length followed by its encoding, a length of 0 when there is none),
+// and the signature (its 4 byte length followed by the signature, a length of 0 when there is none)
+// all integers are big endian, so equal ballots always have the same encoding
+func (b *Ballot) MarshalBinary() ([]byte, error) {
+	if len(b.Suite) > 0xffff || len(b.ElectionID) > 0xffff || len(b.VoterID) > 0xffff {
+		return nil, errors.New("suite name, election ID or voter ID too long to encode")
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteByte(ballotEncodingVersion)
+	writeString(&buffer, b.Suite)
+	writeString(&buffer, b.ElectionID)
+	writeString(&buffer, b.VoterID)
+	binary.Write(&buffer, binary.BigEndian, uint32(len(b.Ciphertexts)))
+	for _, ciphertext := range b.Ciphertexts {
+		data, err := ciphertext.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+	}
+	binary.Write(&buffer, binary.BigEndian, uint32(len(b.Proofs)))
+	for _, proof := range b.Proofs {
+		data, err := proof.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+	}
+	binary.Write(&buffer, binary.BigEndian, uint32(len(b.ChoiceProofs)))
+	for _, proof := range b.ChoiceProofs {
+		data, err := proof.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		binary.Write(&buffer, binary.BigEndian, uint32(len(data)))
+		buffer.Write(data)
+	}
+	var sumProof []byte
+	if b.SumProof != nil {
+		var err error
+		if sumProof, err = b.SumProof.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	binary.Write(&buffer, binary.BigEndian, uint32(len(sumProof)))
+	buffer.Write(sumProof)
+	binary.Write(&buffer, binary.BigEndian, uint32(len(b.Signature)))
+	buffer.Write(b.Signature)
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a ballot encoded by MarshalBinary
+// the points and scalars are decoded with the suite the ballot names, which must be a supported suite
+func (b *Ballot) UnmarshalBinary(data []byte) (err error) {
+	reader := bytes.NewReader(data)
+
+	version, err := reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	if version != ballotEncodingVersion {
+		return fmt.Errorf("unsupported ballot encoding version %d, only version %d is read", version, ballotEncodingVersion)
+	}
+	if b.Suite, err = readString(reader); err != nil {
+		return err
+	}
+	suite, err := FindSuite(b.Suite)
+	if err != nil {
+		return err
+	}
+	if b.ElectionID, err = readString(reader); err != nil {
+		return err
+	}
+	if b.VoterID, err = readString(reader); err != nil {
+		return err
+	}
+
+	// the ciphertexts
+	var count uint32
+	if err = binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return err
+	}
+	if uint64(count)*uint64(2*suite.PointLen()) > uint64(reader.Len()) {
+		return errors.New("unexpected end of ballot")
+	}
+	b.Ciphertexts = make([]*Ciphertext, count)
+	for i := range b.Ciphertexts {
+		if b.Ciphertexts[i], err = DecodeCiphertext(suite, readBytes(reader, 2*suite.PointLen())); err != nil {
+			return fmt.Errorf("ciphertext %d: %v", i, err)
+		}
+	}
+
+	// the proofs
+	if err = binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return err
+	}
+	if uint64(count)*uint64(2*suite.ScalarLen()) > uint64(reader.Len()) {
+		return errors.New("unexpected end of ballot")
+	}
+	b.Proofs = make([]*EncryptionProof, count)
+	for i := range b.Proofs {
+		if b.Proofs[i], err = DecodeEncryptionProof(suite, readBytes(reader, 2*suite.ScalarLen())); err != nil {
+			return fmt.Errorf("proof %d: %v", i, err)
+		}
+	}
+
+	// the choice proofs
+	if err = binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return err
+	}
+	b.ChoiceProofs = nil // left out of ballots that don't choose between candidates
+	for i := 0; i < int(count); i++ {
+		encoded, err := readLengthPrefixed(reader)
+		if err != nil {
+			return err
+		}
+		proof, err := DecodeChoiceProof(suite, encoded)
+		if err != nil {
+			return fmt.Errorf("choice proof %d: %v", i, err)
+		}
+		b.ChoiceProofs = append(b.ChoiceProofs, proof)
+	}
+
+	// the sum proof
+	sumProof, err := readLengthPrefixed(reader)
+	if err != nil {
+		return err
+	}
+	b.SumProof = nil // left out of ballots that aren't homomorphic
+	if len(sumProof) > 0 {
+		if b.SumProof, err = DecodeChoiceProof(suite, sumProof); err != nil {
+			return fmt.Errorf("sum proof: %v", err)
+		}
+	}
+
+	// the signature
+	signature, err := readLengthPrefixed(reader)
+	if err != nil {
+		return err
+	}
+	b.Signature = nil
+	if len(signature) > 0 {
+		b.Signature = signature
+	}
+
+	if reader.Len() != 0 {
+		return fmt.Errorf("%d unexpected bytes at the end of the ballot", reader.Len())
+	}
+	return nil
+}
+
+// the JSON form of a ballot, as it is read back
+// the points and scalars can only be decoded once the suite is known
+type jsonBallot struct {
+	Suite        string
+	ElectionID   string
+	VoterID      string
+	Ciphertexts  []*jsonCiphertext
+	Proofs       []*jsonEncryptionProof
+	ChoiceProofs []*jsonChoiceProof
+	SumProof     *jsonChoiceProof
+	Signature    []byte
+}
+
+// UnmarshalJSON decodes a ballot from its JSON form, in which the points are hex encoded
+// the points and scalars are decoded with the suite the ballot names, which must be a supported suite
+func (b *Ballot) UnmarshalJSON(data []byte) (err error) {
+	var encoded jsonBallot
+	if err = json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	suite, err := FindSuite(encoded.Suite)
+	if err != nil {
+		return err
+	}
+	b.Suite, b.ElectionID, b.VoterID, b.Signature = encoded.Suite, encoded.ElectionID, encoded.VoterID, encoded.Signature
+	if b.Ciphertexts, err = decodeJSONCiphertexts(suite, encoded.Ciphertexts); err != nil {
+		return err
+	}
+	b.Proofs = make([]*EncryptionProof, len(encoded.Proofs))
+	for i := range encoded.Proofs {
+		if b.Proofs[i], err = encoded.Proofs[i].decode(suite); err != nil {
+			return fmt.Errorf("proof %d: %v", i, err)
+		}
+	}
+	b.ChoiceProofs = nil // left out of ballots that don't choose between candidates
+	for i := range encoded.ChoiceProofs {
+		proof, err := encoded.ChoiceProofs[i].decode(suite)
+		if err != nil {
+			return fmt.Errorf("choice proof %d: %v", i, err)
+		}
+		b.ChoiceProofs = append(b.ChoiceProofs, proof)
+	}
+	b.SumProof = nil // left out of ballots that aren't homomorphic
+	if encoded.SumProof != nil {
+		if b.SumProof, err = encoded.SumProof.decode(suite); err != nil {
+			return fmt.Errorf("sum proof: %v", err)
+		}
+	}
+	return nil
+}
+
+// Hash returns the SHA-256 hash of the canonical encoding of the ballot
+func (b *Ballot) Hash() ([]byte, error) {
+	data, err := b.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
+
+// helper function, writes a string prefixed by its 2 byte length
+func writeString(buffer *bytes.Buffer, s string) {
+	binary.Write(buffer, binary.BigEndian, uint16(len(s)))
+	buffer.WriteString(s)
+}
+
+// helper function, reads a string written by writeString
+func readString(reader *bytes.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if int(length) > reader.Len() {
+		return "", errors.New("unexpected end of ballot")
+	}
+	return string(readBytes(reader, int(length))), nil
+}
+
+// helper function, reads bytes prefixed by their 4 byte length
+func readLengthPrefixed(reader *bytes.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if uint64(length) > uint64(reader.Len()) {
+		return nil, errors.New("unexpected end of ballot")
+	}
+	return readBytes(reader, int(length)), nil
+}
+
+// helper function, reads the next n bytes, which the caller made sure are there
+func readBytes(reader *bytes.Reader, n int) []byte {
+	data := make([]byte, n)
+	reader.Read(data)
+	return data
+}
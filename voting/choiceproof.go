@@ -0,0 +1,253 @@
+package voting
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the names of the proofs and encodings of this file, hashed into them
+const (
+	choiceProofDomain = "crypto-voting/choice-proof"
+	candidateDomain   = "crypto-voting/candidate"
+)
+
+// ErrInvalidChoiceProof is returned when a ballot doesn't prove it encrypts one of the allowed candidates
+var ErrInvalidChoiceProof = errors.New("invalid proof of choice")
+
+// ChoiceProof is a disjunctive (CDS) proof that an El Gamal message (g^y, Mh^y) encrypts one of a list of allowed messages
+// for every candidate M_j, it holds a Chaum-Pedersen proof that log_g(g^y) == log_h(Mh^y / M_j)
+// only the proof for the actual choice is real, the others are simulated,
+// and the challenges are tied together so that at most one of them can be simulated freely
+// nothing about which candidate was chosen is revealed
+type ChoiceProof struct {
+	Challenges []kyber.Scalar // c_j, one per candidate, summing to H(...)
+	Responses  []kyber.Scalar // s_j, one per candidate
+}
+
+// EncodeCandidate embeds the name of a candidate into a point
+// the embedding is deterministic, so everyone agrees on the point of every candidate,
+// and the name can be read back with Data() once a ballot is decrypted
+// returns an error wrapping ErrEmbedding if the name is too long to fit in a point
+func EncodeCandidate(suite suites.Suite, name string) (kyber.Point, error) {
+	return embed(suite, []byte(name), suite.XOF([]byte(candidateDomain+"/"+name)))
+}
+
+// EncodeCandidates embeds the names of a list of candidates, in order
+func EncodeCandidates(suite suites.Suite, names []string) (candidates []kyber.Point, err error) {
+	candidates = make([]kyber.Point, len(names))
+	for i, name := range names {
+		if candidates[i], err = EncodeCandidate(suite, name); err != nil {
+			return nil, fmt.Errorf("candidate %d: %w", i, err)
+		}
+	}
+	return // candidates, nil
+}
+
+// ProveChoice proves that a ciphertext encrypts candidates[choice] under pubKey, without revealing which candidate it is
+// given the randomness the ciphertext was encrypted with, and the election and voter the proof is bound to
+// returns an error if choice isn't the index of a candidate
+func ProveChoice(suite suites.Suite, ciphertext *Ciphertext, randomness kyber.Scalar, choice int, candidates []kyber.Point, pubKey kyber.Point, electionID, voterID string) (*ChoiceProof, error) {
+	if choice < 0 || choice >= len(candidates) {
+		return nil, fmt.Errorf("choice %d is not one of the %d candidates", choice, len(candidates))
+	}
+	if ciphertext == nil || ciphertext.C1 == nil || ciphertext.C2 == nil {
+		return nil, errors.New("no ciphertext to prove the choice of")
+	}
+	proof := &ChoiceProof{
+		Challenges: make([]kyber.Scalar, len(candidates)),
+		Responses:  make([]kyber.Scalar, len(candidates)),
+	}
+	commitsG := make([]kyber.Point, len(candidates)) // g^w for the real proof, simulated for the others
+	commitsH := make([]kyber.Point, len(candidates)) // h^w for the real proof, simulated for the others
+
+	nonce := suite.Scalar().Pick(suite.RandomStream()) // w
+	for j := range candidates {
+		if j == choice {
+			commitsG[j] = suite.Point().Mul(nonce, nil)
+			commitsH[j] = suite.Point().Mul(nonce, pubKey)
+			continue
+		}
+
+		// simulate the proof for a candidate that wasn't chosen: pick the challenge and response first,
+		// then work back the commitments that make them hold
+		proof.Challenges[j] = suite.Scalar().Pick(suite.RandomStream())
+		proof.Responses[j] = suite.Scalar().Pick(suite.RandomStream())
+		commitsG[j], commitsH[j] = choiceCommitments(suite, ciphertext, candidates[j], pubKey, proof.Challenges[j], proof.Responses[j])
+	}
+
+	// the real challenge is whatever is left of the overall challenge
+	challenge := choiceChallenge(suite, ciphertext, candidates, pubKey, commitsG, commitsH, electionID, voterID)
+	for j := range candidates {
+		if j != choice {
+			challenge.Sub(challenge, proof.Challenges[j])
+		}
+	}
+	proof.Challenges[choice] = challenge
+	proof.Responses[choice] = suite.Scalar().Mul(challenge, randomness) // c*y
+	proof.Responses[choice].Add(proof.Responses[choice], nonce)         // w + c*y
+
+	return proof, nil
+}
+
+// VerifyChoice checks that a ciphertext encrypts one of the candidates under pubKey,
+// for the given election and voter
+func VerifyChoice(suite suites.Suite, ciphertext *Ciphertext, proof *ChoiceProof, candidates []kyber.Point, pubKey kyber.Point, electionID, voterID string) error {
+	if proof == nil || len(proof.Challenges) != len(candidates) || len(proof.Responses) != len(candidates) {
+		return fmt.Errorf("%w: proof doesn't cover the %d candidates", ErrInvalidChoiceProof, len(candidates))
+	}
+	if ciphertext == nil || ciphertext.C1 == nil || ciphertext.C2 == nil {
+		return fmt.Errorf("%w: ciphertext is missing", ErrInvalidChoiceProof)
+	}
+
+	// recover the commitments of every branch, and add up the challenges
+	commitsG := make([]kyber.Point, len(candidates))
+	commitsH := make([]kyber.Point, len(candidates))
+	sum := suite.Scalar().Zero()
+	for j := range candidates {
+		if proof.Challenges[j] == nil || proof.Responses[j] == nil {
+			return fmt.Errorf("%w: branch %d is missing", ErrInvalidChoiceProof, j)
+		}
+		commitsG[j], commitsH[j] = choiceCommitments(suite, ciphertext, candidates[j], pubKey, proof.Challenges[j], proof.Responses[j])
+		sum.Add(sum, proof.Challenges[j])
+	}
+
+	// the challenges only add up if at most one branch was simulated
+	if !choiceChallenge(suite, ciphertext, candidates, pubKey, commitsG, commitsH, electionID, voterID).Equal(sum) {
+		return ErrInvalidChoiceProof
+	}
+	return nil
+}
+
+// the commitments of the branch of a candidate M, given its challenge c and response s
+// g^s / (g^y)^c and h^s / (Mh^y / M)^c
+func choiceCommitments(suite suites.Suite, ciphertext *Ciphertext, candidate, pubKey kyber.Point, challenge, response kyber.Scalar) (commitG, commitH kyber.Point) {
+	commitG = suite.Point().Mul(response, nil)
+	commitG.Sub(commitG, suite.Point().Mul(challenge, ciphertext.C1))
+
+	unblinded := suite.Point().Sub(ciphertext.C2, candidate) // h^y, if M is the candidate
+	commitH = suite.Point().Mul(response, pubKey)
+	commitH.Sub(commitH, suite.Point().Mul(challenge, unblinded))
+	return // commitG, commitH
+}
+
+// the overall challenge of a choice proof
+func choiceChallenge(suite suites.Suite, ciphertext *Ciphertext, candidates []kyber.Point, pubKey kyber.Point, commitsG, commitsH []kyber.Point, electionID, voterID string) kyber.Scalar {
+	parts := [][]byte{[]byte(electionID), []byte(voterID)}
+	parts = append(parts, pointBytes(pubKey, ciphertext.C1, ciphertext.C2)...)
+	parts = append(parts, pointBytes(candidates...)...)
+	parts = append(parts, pointBytes(commitsG...)...)
+	parts = append(parts, pointBytes(commitsH...)...)
+	return hashChallenge(suite, choiceProofDomain, parts...)
+}
+
+// MarshalBinary encodes the proof as the number of candidates (4 bytes, big endian),
+// followed by the challenge and response of every candidate
+func (p *ChoiceProof) MarshalBinary() ([]byte, error) {
+	if p == nil {
+		return nil, errors.New("choice proof is missing")
+	}
+	if len(p.Responses) != len(p.Challenges) {
+		return nil, errors.New("choice proof has a different number of challenges and responses")
+	}
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(p.Challenges)))
+	for j := range p.Challenges {
+		if p.Challenges[j] == nil || p.Responses[j] == nil {
+			return nil, fmt.Errorf("branch %d of the choice proof is missing", j)
+		}
+		challenge, err := p.Challenges[j].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		response, err := p.Responses[j].MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, challenge...)
+		data = append(data, response...)
+	}
+	return data, nil
+}
+
+// DecodeChoiceProof decodes a proof encoded by MarshalBinary, with scalars of the given suite
+func DecodeChoiceProof(suite suites.Suite, data []byte) (p *ChoiceProof, err error) {
+	if len(data) < 4 {
+		return nil, errors.New("choice proof is too short")
+	}
+	count := int(binary.BigEndian.Uint32(data))
+	scalarLen := suite.ScalarLen()
+	if uint64(len(data)-4) != uint64(count)*uint64(2*scalarLen) {
+		return nil, fmt.Errorf("choice proof is %d bytes long, expected %d", len(data), 4+uint64(count)*uint64(2*scalarLen))
+	}
+	p = &ChoiceProof{
+		Challenges: make([]kyber.Scalar, count),
+		Responses:  make([]kyber.Scalar, count),
+	}
+	for j := 0; j < count; j++ {
+		offset := 4 + j*2*scalarLen
+		if p.Challenges[j], err = decodeScalar(suite, data[offset:offset+scalarLen]); err != nil {
+			return nil, err
+		}
+		if p.Responses[j], err = decodeScalar(suite, data[offset+scalarLen:offset+2*scalarLen]); err != nil {
+			return nil, err
+		}
+	}
+	return // p, nil
+}
+
+// the JSON form of a proof, with every scalar hex encoded
+type jsonChoiceProof struct {
+	Challenges []string
+	Responses  []string
+}
+
+// MarshalJSON encodes the proof as a JSON object with every scalar hex encoded
+func (p *ChoiceProof) MarshalJSON() ([]byte, error) {
+	challenges, err := encodeScalars(p.Challenges)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := encodeScalars(p.Responses)
+	if err != nil {
+		return nil, err
+	}
+	encoded := jsonChoiceProof{Challenges: make([]string, len(challenges)), Responses: make([]string, len(responses))}
+	for j := range challenges {
+		encoded.Challenges[j] = hex.EncodeToString(challenges[j])
+	}
+	for j := range responses {
+		encoded.Responses[j] = hex.EncodeToString(responses[j])
+	}
+	return json.Marshal(encoded)
+}
+
+// decodes the JSON form of a proof, with scalars of the given suite
+func (encoded *jsonChoiceProof) decode(suite suites.Suite) (p *ChoiceProof, err error) {
+	if encoded == nil {
+		return nil, fmt.Errorf("%w: proof is missing", ErrInvalidChoiceProof)
+	}
+	p = &ChoiceProof{}
+	if p.Challenges, err = decodeHexScalars(suite, encoded.Challenges); err != nil {
+		return nil, err
+	}
+	if p.Responses, err = decodeHexScalars(suite, encoded.Responses); err != nil {
+		return nil, err
+	}
+	return // p, nil
+}
+
+// helper function, decodes a list of hex encoded scalars
+func decodeHexScalars(suite suites.Suite, encoded []string) (scalars []kyber.Scalar, err error) {
+	raw := make([][]byte, len(encoded))
+	for i, s := range encoded {
+		if raw[i], err = hex.DecodeString(s); err != nil {
+			return nil, err
+		}
+	}
+	return decodeScalars(suite, raw)
+}
@@ -0,0 +1,166 @@
+package voting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// Ciphertext is an El Gamal encrypted message (g^y, Mg^(xy))
+// it is the pair held at the same index of the elGamal1 and elGamal2 lists used throughout the package
+type Ciphertext struct {
+	C1 kyber.Point // g^y, the first half of the pair
+	C2 kyber.Point // Mg^(xy), the second half of the pair
+}
+
+// EncryptCiphertext encrypts an El Gamal message, as EncryptMessage does, but returns it as a Ciphertext
+func EncryptCiphertext(suite suites.Suite, message, pubKey kyber.Point) *Ciphertext {
+	elGamal1, elGamal2 := EncryptMessage(suite, message, pubKey)
+	return &Ciphertext{C1: elGamal1, C2: elGamal2}
+}
+
+// JoinCiphertexts pairs up the two halves of a list of El Gamal messages
+// in pseudocode: ciphertexts[i] == (elGamal1[i], elGamal2[i])
+// the halves must have the same length
+func JoinCiphertexts(elGamal1, elGamal2 []kyber.Point) (ciphertexts []*Ciphertext, err error) {
+	if len(elGamal1) != len(elGamal2) {
+		return nil, fmt.Errorf("el gamal lists have different lengths (%d and %d)", len(elGamal1), len(elGamal2))
+	}
+	ciphertexts = make([]*Ciphertext, len(elGamal1))
+	for i := range elGamal1 {
+		ciphertexts[i] = &Ciphertext{C1: elGamal1[i], C2: elGamal2[i]}
+	}
+	return // ciphertexts, nil
+}
+
+// SplitCiphertexts splits a list of El Gamal messages into its two halves,
+// as expected by ShuffleAndCheck and DecryptMessages
+// every ciphertext of the list must be present
+func SplitCiphertexts(ciphertexts []*Ciphertext) (elGamal1, elGamal2 []kyber.Point, err error) {
+	elGamal1 = make([]kyber.Point, len(ciphertexts))
+	elGamal2 = make([]kyber.Point, len(ciphertexts))
+	for i, ciphertext := range ciphertexts {
+		if ciphertext == nil {
+			return nil, nil, fmt.Errorf("ciphertext %d is missing", i)
+		}
+		elGamal1[i] = ciphertext.C1
+		elGamal2[i] = ciphertext.C2
+	}
+	return // elGamal1, elGamal2, nil
+}
+
+// Equal tells whether two ciphertexts are the same pair of points
+func (c *Ciphertext) Equal(other *Ciphertext) bool {
+	return c.C1.Equal(other.C1) && c.C2.Equal(other.C2)
+}
+
+// MarshalBinary encodes the ciphertext as the binary encoding of C1 followed by that of C2
+// points of a suite all have the same length, so the encoding is canonical
+func (c *Ciphertext) MarshalBinary() ([]byte, error) {
+	if c == nil || c.C1 == nil || c.C2 == nil {
+		return nil, errors.New("ciphertext is missing")
+	}
+	c1, err := c.C1.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	c2, err := c.C2.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(c1, c2...), nil
+}
+
+// DecodeCiphertext decodes a ciphertext encoded by MarshalBinary, with points of the given suite
+func DecodeCiphertext(suite suites.Suite, data []byte) (c *Ciphertext, err error) {
+	pointLen := suite.PointLen()
+	if len(data) != 2*pointLen {
+		return nil, fmt.Errorf("ciphertext is %d bytes long, expected %d", len(data), 2*pointLen)
+	}
+	c = &Ciphertext{}
+	if c.C1, err = decodePoint(suite, data[:pointLen]); err != nil {
+		return nil, err
+	}
+	if c.C2, err = decodePoint(suite, data[pointLen:]); err != nil {
+		return nil, err
+	}
+	return // c, nil
+}
+
+// the JSON form of a ciphertext, with both points hex encoded
+type jsonCiphertext struct {
+	C1 string
+	C2 string
+}
+
+// MarshalJSON encodes the ciphertext as a JSON object with both points hex encoded
+func (c *Ciphertext) MarshalJSON() ([]byte, error) {
+	encoded, err := c.encodeJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+// the JSON form of the ciphertext
+func (c *Ciphertext) encodeJSON() (*jsonCiphertext, error) {
+	c1, err := c.C1.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	c2, err := c.C2.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &jsonCiphertext{C1: hex.EncodeToString(c1), C2: hex.EncodeToString(c2)}, nil
+}
+
+// helper function, the JSON form of a list of ciphertexts
+func encodeJSONCiphertexts(ciphertexts []*Ciphertext) (encoded []*jsonCiphertext, err error) {
+	encoded = make([]*jsonCiphertext, len(ciphertexts))
+	for i, ciphertext := range ciphertexts {
+		if encoded[i], err = ciphertext.encodeJSON(); err != nil {
+			return nil, err
+		}
+	}
+	return // encoded, nil
+}
+
+// decodes the JSON form of a ciphertext, with points of the given suite
+// the JSON form doesn't name its suite, so it is decoded by whatever holds it (a ballot, a mix record...)
+func (encoded *jsonCiphertext) decode(suite suites.Suite) (c *Ciphertext, err error) {
+	if encoded == nil {
+		return nil, errors.New("ciphertext is missing")
+	}
+	c1, err := hex.DecodeString(encoded.C1)
+	if err != nil {
+		return nil, err
+	}
+	c2, err := hex.DecodeString(encoded.C2)
+	if err != nil {
+		return nil, err
+	}
+	c = &Ciphertext{}
+	if c.C1, err = decodePoint(suite, c1); err != nil {
+		return nil, err
+	}
+	if c.C2, err = decodePoint(suite, c2); err != nil {
+		return nil, err
+	}
+	return // c, nil
+}
+
+// helper function, decodes the JSON form of a list of ciphertexts
+func decodeJSONCiphertexts(suite suites.Suite, encoded []*jsonCiphertext) (ciphertexts []*Ciphertext, err error) {
+	ciphertexts = make([]*Ciphertext, len(encoded))
+	for i := range encoded {
+		if ciphertexts[i], err = encoded[i].decode(suite); err != nil {
+			return nil, fmt.Errorf("ciphertext %d: %v", i, err)
+		}
+	}
+	return // ciphertexts, nil
+}
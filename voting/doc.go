@@ -0,0 +1,38 @@
+// Package voting is a proof of concept for a threshold El Gamal voting system.
+//
+// Every function takes the cipher suite of the election as its first argument (see FindSuite),
+// and the artifacts that get saved or sent around are tagged with the name of their suite.
+//
+// An election runs in four steps:
+//
+// 1) Setup: CreateThresholdShares runs a distributed key generation between the trustees.
+// Every trustee ends up with a share of the private key, and all shares hold the same public key.
+//
+// 2) Ballot encryption: EncryptMessage (or EncryptLongMessage for longer ballots)
+// encrypts an embedded ballot under the public key as an El Gamal pair.
+// EncryptBallot also proves knowledge of the encryption randomness, bound to the voter and election,
+// and IngestBallots checks those proofs and turns away copied ballots before they are mixed.
+// With a voter Registry, voters sign their ballots, and IngestSignedBallots also turns away
+// unknown voters, bad signatures and second ballots, as the registry's RevotePolicy says.
+//
+// 3) Mixing: ShuffleAndCheck re-encrypts and permutes the list of ballots,
+// proving and verifying that the shuffle was done correctly.
+//
+// 4) Tallying: DecryptMessages has every trustee extract a shadow of each ballot,
+// and combines the shadows to decrypt it without ever rebuilding the private key.
+// Every shadow carries a Chaum-Pedersen proof, which VerifyShadow checks against the
+// dkg commitments before the shadow is used.
+// CompileMessages puts long ballots back together after decryption,
+// checking the length and integrity tag every long ballot carries.
+// ReassembleMessages does the same under a ReassemblyPolicy, reporting on every ballot.
+// A BallotSchema encodes typed selections into long ballots, and decodes them back after decryption.
+// A Tally counts them per question, and signs the result as a TallyResult.
+// Long ballots can also be kept as a CiphertextVector, shuffled as a unit by ShuffleVectors,
+// and decrypted whole by DecryptLongMessages.
+//
+// Election runs these steps for an election defined by a Manifest,
+// and refuses the operations its current Phase doesn't allow.
+//
+// Nothing in the package panics on bad input: errors are returned, wrapping sentinel errors
+// such as ErrInvalidShuffleProof or ErrInsufficientShadows that callers can check with errors.Is.
+package voting
@@ -0,0 +1,743 @@
+package voting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.dedis.ch/kyber/v3"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+
+	"github.com/SpencerBouck/crypto-voting/board"
+)
+
+// Phase is a step in the lifecycle of an election
+// an election only ever moves forward, one phase at a time
+type Phase int
+
+// the phases of an election, in the order they run
+const (
+	PhaseKeyCeremony  Phase = iota // the trustees are generating the election key
+	PhaseVotingOpen                // ballots are being cast
+	PhaseVotingClosed              // no more ballots are accepted, mixing hasn't started
+	PhaseMixing                    // the ballots are going through the mixnet
+	PhaseDecrypting                // the mixed ballots are waiting for the trustees to decrypt them
+	PhaseTallied                   // the results are known
+)
+
+// the names of the phases, as they appear in errors
+var phaseNames = []string{"key ceremony", "voting open", "voting closed", "mixing", "decrypting", "tallied"}
+
+// String returns the name of the phase
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phaseNames) {
+		return fmt.Sprintf("phase %d", int(p))
+	}
+	return phaseNames[p]
+}
+
+// ErrWrongPhase is returned for an operation the election doesn't allow in its current phase
+var ErrWrongPhase = errors.New("operation not allowed in this phase of the election")
+
+// ErrOutsideVotingWindow is returned when voting would open, close or take a ballot outside the window of the manifest
+var ErrOutsideVotingWindow = errors.New("outside the voting window")
+
+// Election runs an election defined by a manifest, through its phases
+// a ballot holds one ciphertext per question, each proving that it encrypts one of the question's candidates,
+// and the answers to each question are mixed and decrypted on their own
+// an election is safe to use from several goroutines, e.g. to cast ballots concurrently
+type Election struct {
+	Manifest *Manifest    // the manifest of the election
+	Suite    suites.Suite // the cipher suite named by the manifest
+
+	// Now tells the time, to check operations against the voting window
+	// defaults to time.Now, and can be replaced to run an election on another clock
+	Now func() time.Time
+
+	mu          sync.Mutex
+	phase       Phase
+	candidates  [][]kyber.Point        // the encoded candidates of each question
+	publicKey   kyber.Point            // the public key of the election, once the key ceremony is over
+	commits     []kyber.Point          // the public commitments of the dkg, to verify the trustees' shadows
+	ballots     []*Ballot              // the ballots cast so far, in order
+	seen        map[string]bool        // the first halves of the ciphertexts cast so far
+	spoiled     []*SpoiledBallot       // the ballots spoiled so far, in order
+	spoiledKeys map[string]bool        // the first halves of the ciphertexts spoiled so far
+	mixRecords  [][]*MixRecord         // the records of the mixnet, for each question
+	mixed       [][]*Ciphertext        // the output of the mixnet, for each question
+	partials    [][]*PartialDecryption // the partial decryptions of the trustees, for each question
+	counts      [][]int                // the number of votes of each candidate, for each question
+	misbehaving []int                  // the trustees whose shadows failed verification
+	registry    *Registry              // the voters allowed to cast ballots, if the election checks eligibility
+	voted       map[string]bool        // the voters who cast a ballot so far
+
+	board    *board.Board // the bulletin board the artifacts of the election are posted to, if any
+	boardKey kyber.Scalar // the key the election signs its board entries with
+}
+
+// PublishedKey is how the outcome of the key ceremony is posted to the bulletin board
+type PublishedKey struct {
+	Suite       string        // the name of the cipher suite of the election
+	PublicKey   kyber.Point   // the public key of the election
+	Commitments []kyber.Point // the public commitments of the dkg
+}
+
+// the JSON form of a published key, with every point hex encoded
+type jsonPublishedKey struct {
+	Suite       string
+	PublicKey   string
+	Commitments []string
+}
+
+// MarshalJSON encodes the published key as a JSON object
+func (k *PublishedKey) MarshalJSON() ([]byte, error) {
+	points, err := encodePoints(append([]kyber.Point{k.PublicKey}, k.Commitments...))
+	if err != nil {
+		return nil, err
+	}
+	encoded := jsonPublishedKey{Suite: k.Suite, PublicKey: hex.EncodeToString(points[0]), Commitments: make([]string, len(k.Commitments))}
+	for i := range k.Commitments {
+		encoded.Commitments[i] = hex.EncodeToString(points[i+1])
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a published key encoded by MarshalJSON, with the suite it names
+func (k *PublishedKey) UnmarshalJSON(data []byte) error {
+	var encoded jsonPublishedKey
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	suite, err := FindSuite(encoded.Suite)
+	if err != nil {
+		return err
+	}
+	raw := make([][]byte, 1+len(encoded.Commitments))
+	for i, point := range append([]string{encoded.PublicKey}, encoded.Commitments...) {
+		if raw[i], err = hex.DecodeString(point); err != nil {
+			return err
+		}
+	}
+	points, err := decodePoints(suite, raw)
+	if err != nil {
+		return err
+	}
+	k.Suite = encoded.Suite
+	k.PublicKey = points[0]
+	k.Commitments = points[1:]
+	return nil
+}
+
+// PublishedResult is how the counts are posted to the bulletin board once the election is decrypted
+type PublishedResult struct {
+	Counts [][]int // the number of votes of each candidate, for each question, in the order of the manifest
+}
+
+// PublishedBallotRoot is how the root of the tree of every cast ballot is posted to the bulletin board when voting closes
+type PublishedBallotRoot struct {
+	Size int    // the number of ballots in the tree
+	Root []byte // the root of the tree
+}
+
+// PublishedMixRecord is how a mix record is posted to the bulletin board, along with the question it mixed
+type PublishedMixRecord struct {
+	Question string     // the ID of the question
+	Record   *MixRecord // the record of the mix server
+}
+
+// PublishedPartialDecryption is how a partial decryption is posted to the bulletin board, along with the question it decrypts
+type PublishedPartialDecryption struct {
+	Question   string             // the ID of the question
+	Decryption *PartialDecryption // the partial decryption of the trustee
+}
+
+// NewElection creates an election from its manifest, in the key ceremony phase
+func NewElection(manifest *Manifest) (*Election, error) {
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	suite, err := FindSuite(manifest.Suite)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+	candidates := make([][]kyber.Point, len(manifest.Questions))
+	for i, question := range manifest.Questions {
+		if candidates[i], err = EncodeCandidates(suite, question.Candidates); err != nil {
+			return nil, fmt.Errorf("%w: question %q: %w", ErrInvalidManifest, question.ID, err)
+		}
+	}
+	return &Election{
+		Manifest:    manifest,
+		Suite:       suite,
+		Now:         time.Now,
+		phase:       PhaseKeyCeremony,
+		candidates:  candidates,
+		seen:        make(map[string]bool),
+		spoiledKeys: make(map[string]bool),
+		voted:       make(map[string]bool),
+	}, nil
+}
+
+// Phase returns the current phase of the election
+func (e *Election) Phase() Phase {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.phase
+}
+
+// PublicKey returns the public key of the election, or nil before the key ceremony is over
+func (e *Election) PublicKey() kyber.Point {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.publicKey
+}
+
+// Commitments returns the public commitments of the dkg, or nil before the key ceremony is over
+func (e *Election) Commitments() []kyber.Point {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.commits
+}
+
+// Candidates returns the encoded candidates of a question, by index
+func (e *Election) Candidates(question int) ([]kyber.Point, error) {
+	if question < 0 || question >= len(e.candidates) {
+		return nil, fmt.Errorf("the election has no question %d, only %d", question, len(e.candidates))
+	}
+	return e.candidates[question], nil
+}
+
+// UseBoard has the election post its artifacts to a bulletin board, signed with the given key:
+// the manifest right away, then the election key, the voter registry, every ballot cast, every mix record and every partial decryption
+// must be called during the key ceremony, so the board holds the whole election
+func (e *Election) UseBoard(b *board.Board, key kyber.Scalar) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("attach a bulletin board", PhaseKeyCeremony); err != nil {
+		return err
+	}
+	e.board = b
+	e.boardKey = key
+	if err := e.publish(board.KindManifest, e.Manifest); err != nil {
+		return err
+	}
+	if e.publicKey != nil {
+		if err := e.publish(board.KindKey, &PublishedKey{Suite: e.Suite.String(), PublicKey: e.publicKey, Commitments: e.commits}); err != nil {
+			return err
+		}
+	}
+	if e.registry != nil {
+		return e.publish(board.KindRegistry, e.registry)
+	}
+	return nil
+}
+
+// UseRegistry restricts the election to the voters of a registry
+// every ballot must then be signed by its voter (see Ballot.Sign), and the policy of the registry decides
+// which ballot counts when a voter casts several; the registry is posted to the board, so anyone can check this
+// must be called during the key ceremony, before any ballot is cast
+func (e *Election) UseRegistry(registry *Registry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("attach a voter registry", PhaseKeyCeremony); err != nil {
+		return err
+	}
+	if err := checkSuite(e.Suite, registry.Suite, "voter registry"); err != nil {
+		return err
+	}
+	if registry.ElectionID != e.Manifest.ElectionID {
+		return fmt.Errorf("the registry is for election %q, not %q", registry.ElectionID, e.Manifest.ElectionID)
+	}
+	if _, err := registry.Policy.MarshalText(); err != nil {
+		return err
+	}
+	if err := e.publish(board.KindRegistry, registry); err != nil {
+		return err
+	}
+	e.registry = registry
+	return nil
+}
+
+// helper function, posts an artifact to the bulletin board, if the election has one
+// must be called with the lock held
+func (e *Election) publish(kind board.Kind, payload interface{}) error {
+	if e.board == nil {
+		return nil
+	}
+	if _, err := e.board.Append(e.boardKey, kind, payload); err != nil {
+		return fmt.Errorf("posting %s to the bulletin board: %v", kind, err)
+	}
+	return nil
+}
+
+// helper function, refuses an operation unless the election is in one of the allowed phases
+// must be called with the lock held
+func (e *Election) require(operation string, allowed ...Phase) error {
+	for _, phase := range allowed {
+		if e.phase == phase {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: can't %s while %s", ErrWrongPhase, operation, e.phase)
+}
+
+// CompleteKeyCeremony records the outcome of the key ceremony: the public key and the dkg commitments
+// these come from any trustee's share, as every trustee ends the ceremony with the same ones
+// they are posted to the board, for verifiers to check the shadows of the trustees
+func (e *Election) CompleteKeyCeremony(publicKey kyber.Point, commits []kyber.Point) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("complete the key ceremony", PhaseKeyCeremony); err != nil {
+		return err
+	}
+	if len(commits) != e.Manifest.Threshold {
+		return fmt.Errorf("%d commitments given, threshold is %d", len(commits), e.Manifest.Threshold)
+	}
+	// the first commitment of the dkg is the public key itself
+	if !commits[0].Equal(publicKey) {
+		return errors.New("the public key doesn't match the commitments")
+	}
+	if err := e.publish(board.KindKey, &PublishedKey{Suite: e.Suite.String(), PublicKey: publicKey, Commitments: commits}); err != nil {
+		return err
+	}
+	e.publicKey = publicKey
+	e.commits = commits
+	return nil
+}
+
+// OpenVoting opens the election to ballots
+// the key ceremony must be complete, and the voting window must have started
+func (e *Election) OpenVoting() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("open voting", PhaseKeyCeremony); err != nil {
+		return err
+	}
+	if e.publicKey == nil {
+		return fmt.Errorf("%w: can't open voting before the key ceremony is complete", ErrWrongPhase)
+	}
+	if now := e.Now(); now.Before(e.Manifest.VotingStart) {
+		return fmt.Errorf("%w: voting starts at %s", ErrOutsideVotingWindow, e.Manifest.VotingStart)
+	}
+	e.phase = PhaseVotingOpen
+	return nil
+}
+
+// EncryptBallot encrypts a voter's answers, one candidate index per question, into a ballot that can be cast
+// this is what the voter's device does, it doesn't change the election
+// with a voter registry, the voter signs the ballot before casting it
+func (e *Election) EncryptBallot(choices []int, voterID string) (*Ballot, error) {
+	pending, err := e.PrepareBallot(choices, voterID)
+	if err != nil {
+		return nil, err
+	}
+	return pending.Cast(), nil
+}
+
+// PrepareBallot encrypts a voter's answers as EncryptBallot does, but keeps the randomness until the voter decides
+// to cast the ballot (PendingBallot.Cast, then Cast) or to audit it (PendingBallot.Spoil, then Spoil)
+func (e *Election) PrepareBallot(choices []int, voterID string) (*PendingBallot, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("encrypt a ballot", PhaseVotingOpen); err != nil {
+		return nil, err
+	}
+	if len(choices) != len(e.candidates) {
+		return nil, fmt.Errorf("%d choices given for %d questions", len(choices), len(e.candidates))
+	}
+	electionID := e.Manifest.ElectionID
+	pending := &PendingBallot{
+		Ballot: &Ballot{
+			Suite:        e.Suite.String(),
+			ElectionID:   electionID,
+			VoterID:      voterID,
+			Ciphertexts:  make([]*Ciphertext, len(choices)),
+			Proofs:       make([]*EncryptionProof, len(choices)),
+			ChoiceProofs: make([]*ChoiceProof, len(choices)),
+		},
+		messages:   make([]kyber.Point, len(choices)),
+		randomness: make([]kyber.Scalar, len(choices)),
+	}
+	ballot := pending.Ballot
+	for i, choice := range choices {
+		candidates := e.candidates[i]
+		if choice < 0 || choice >= len(candidates) {
+			return nil, fmt.Errorf("question %q: choice %d is not one of the %d candidates", e.Manifest.Questions[i].ID, choice, len(candidates))
+		}
+		elGamal1, elGamal2, randomness := EncryptMessageWithRandomness(e.Suite, candidates[choice], e.publicKey)
+		ballot.Ciphertexts[i] = &Ciphertext{C1: elGamal1, C2: elGamal2}
+		ballot.Proofs[i] = ProveEncryption(e.Suite, ballot.Ciphertexts[i], randomness, electionID, voterID)
+		proof, err := ProveChoice(e.Suite, ballot.Ciphertexts[i], randomness, choice, candidates, e.publicKey, electionID, voterID)
+		if err != nil {
+			return nil, fmt.Errorf("question %q: %w", e.Manifest.Questions[i].ID, err)
+		}
+		ballot.ChoiceProofs[i] = proof
+		pending.messages[i] = candidates[choice]
+		pending.randomness[i] = randomness
+	}
+	return pending, nil
+}
+
+// Spoil audits a ballot instead of casting it: the revealed answers must give back every ciphertext of the ballot
+// the spoiled ballot is recorded, posted to the board, and can never be cast, so it stays out of the tally
+// returns the names of the candidates the ballot opened to, one per question, for the voter to compare with their choices
+func (e *Election) Spoil(spoiled *SpoiledBallot) (names []string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("spoil a ballot", PhaseVotingOpen); err != nil {
+		return nil, err
+	}
+	if spoiled == nil || spoiled.Ballot == nil {
+		return nil, fmt.Errorf("%w: the ballot is missing", ErrInvalidSpoiledBallot)
+	}
+	if err := spoiled.Ballot.Verify(e.Suite, e.Manifest.ElectionID); err != nil {
+		return nil, err
+	}
+	if err := spoiled.Verify(e.Suite, e.publicKey); err != nil {
+		return nil, err
+	}
+	if names, err = spoiledAnswers(e.Manifest, e.candidates, spoiled); err != nil {
+		return nil, err
+	}
+
+	// a ballot already cast has been counted, revealing it now would only expose the vote
+	if _, err := freshCiphertexts(e.seen, spoiled.Ballot); err != nil {
+		return nil, err
+	}
+	keys, err := freshCiphertexts(e.spoiledKeys, spoiled.Ballot)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSpoiledBallot, err)
+	}
+	if err := e.publish(board.KindSpoiledBallot, spoiled); err != nil {
+		return nil, err
+	}
+	for key := range keys {
+		e.spoiledKeys[key] = true
+	}
+	e.spoiled = append(e.spoiled, spoiled)
+	return names, nil
+}
+
+// helper function, the names of the candidates a spoiled ballot opened to, one per question
+func spoiledAnswers(manifest *Manifest, candidates [][]kyber.Point, spoiled *SpoiledBallot) (names []string, err error) {
+	if len(spoiled.Messages) != len(candidates) {
+		return nil, fmt.Errorf("%w: ballot answers %d questions, the election has %d", ErrInvalidSpoiledBallot, len(spoiled.Messages), len(candidates))
+	}
+	names = make([]string, len(candidates))
+	for i, message := range spoiled.Messages {
+		j := candidateIndex(candidates[i], message)
+		if j < 0 {
+			return nil, fmt.Errorf("%w: question %q: the answer is none of the candidates", ErrInvalidSpoiledBallot, manifest.Questions[i].ID)
+		}
+		names[i] = manifest.Questions[i].Candidates[j]
+	}
+	return // names, nil
+}
+
+// helper function, the index of the candidate a plaintext is, or -1 if it is none of them
+func candidateIndex(candidates []kyber.Point, plaintext kyber.Point) int {
+	for k := range candidates {
+		if plaintext.Equal(candidates[k]) {
+			return k
+		}
+	}
+	return -1
+}
+
+// helper function, counts the votes of each candidate among the decrypted answers to a question
+// every answer was proven to be one of the candidates when it was cast, so any other plaintext is an error
+func countAnswers(candidates []kyber.Point, decrypted []kyber.Point) (counts []int, err error) {
+	counts = make([]int, len(candidates))
+	for j, plaintext := range decrypted {
+		k := candidateIndex(candidates, plaintext)
+		if k < 0 {
+			return nil, fmt.Errorf("answer %d decrypted to none of the candidates", j)
+		}
+		counts[k]++
+	}
+	return // counts, nil
+}
+
+// Spoiled returns the ballots spoiled so far, in order
+func (e *Election) Spoiled() []*SpoiledBallot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*SpoiledBallot(nil), e.spoiled...)
+}
+
+// Cast checks a ballot and adds it to the election
+// the ballot must be cast within the voting window, hold a valid answer to every question, and not copy a ballot already cast
+// with a voter registry, the ballot must also be signed by a registered voter, and a voter casting again is turned away
+// under FirstVoteCounts, while under LastVoteCounts the new ballot replaces their previous one in the tally
+// returns the tracking code of the ballot, which the voter keeps to check later that their ballot was counted (see Track)
+func (e *Election) Cast(ballot *Ballot) (code string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("cast a ballot", PhaseVotingOpen); err != nil {
+		return "", err
+	}
+	if now := e.Now(); !now.Before(e.Manifest.VotingEnd) {
+		return "", fmt.Errorf("%w: voting ended at %s", ErrOutsideVotingWindow, e.Manifest.VotingEnd)
+	}
+	if err := verifyElectionBallot(e.Suite, e.Manifest, e.candidates, e.publicKey, ballot); err != nil {
+		return "", err
+	}
+	// a spoiled ballot is refused whoever signs it, its ciphertexts have been opened
+	if _, err := freshCiphertexts(e.spoiledKeys, ballot); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSpoiledBallot, err)
+	}
+	if e.registry != nil {
+		if err := e.registry.VerifyBallot(e.Suite, ballot); err != nil {
+			return "", err
+		}
+		if e.voted[ballot.VoterID] && e.registry.Policy == FirstVoteCounts {
+			return "", fmt.Errorf("%w: voter %q", ErrAlreadyVoted, ballot.VoterID)
+		}
+	}
+	if code, err = ballot.TrackingCode(); err != nil {
+		return "", err
+	}
+	keys, err := freshCiphertexts(e.seen, ballot)
+	if err != nil {
+		return "", err
+	}
+	if err := e.publish(board.KindBallot, ballot); err != nil {
+		return "", err
+	}
+	for key := range keys {
+		e.seen[key] = true
+	}
+	e.voted[ballot.VoterID] = true
+	e.ballots = append(e.ballots, ballot)
+	return code, nil
+}
+
+// helper function, the cast ballots that count: all of them, or the one picked by the registry's policy for each voter
+// must be called with the lock held
+func (e *Election) countedBallots() []*Ballot {
+	if e.registry == nil {
+		return e.ballots
+	}
+	return countedBallots(e.registry, e.ballots)
+}
+
+// helper function, the ballots that count among cast ballots that all passed the registry, under its policy
+func countedBallots(registry *Registry, ballots []*Ballot) []*Ballot {
+	indices := make([]int, len(ballots))
+	for i := range ballots {
+		indices[i] = i
+	}
+	counted, _ := applyRevotePolicy(ballots, indices, registry.Policy)
+	return pickBallots(ballots, counted)
+}
+
+// helper function, checks that a ballot belongs to the election, and holds a valid answer to every question
+// anyone can run this check, with the manifest and the public key of the election
+func verifyElectionBallot(suite suites.Suite, manifest *Manifest, candidates [][]kyber.Point, pubKey kyber.Point, ballot *Ballot) error {
+	if err := ballot.Verify(suite, manifest.ElectionID); err != nil {
+		return err
+	}
+	if len(ballot.Ciphertexts) != len(candidates) || len(ballot.ChoiceProofs) != len(candidates) {
+		return fmt.Errorf("ballot answers %d questions, the election has %d", len(ballot.Ciphertexts), len(candidates))
+	}
+	for i, ciphertext := range ballot.Ciphertexts {
+		if err := VerifyChoice(suite, ciphertext, ballot.ChoiceProofs[i], candidates[i], pubKey, ballot.ElectionID, ballot.VoterID); err != nil {
+			return fmt.Errorf("question %q: %w", manifest.Questions[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// Ballots returns the ballots cast so far, in order, including those a later ballot of the same voter replaced
+func (e *Election) Ballots() []*Ballot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Ballot(nil), e.ballots...)
+}
+
+// Track looks up a cast ballot by its tracking code, and proves it is among the ballots cast so far
+// returns the proof and the root of the tree of every ballot cast so far,
+// which is the root posted to the board when voting closes once the election is past voting
+func (e *Election) Track(code string) (proof *InclusionProof, root []byte, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("track a ballot", PhaseVotingOpen, PhaseVotingClosed, PhaseMixing, PhaseDecrypting, PhaseTallied); err != nil {
+		return nil, nil, err
+	}
+	tree, err := NewBallotTree(e.ballots)
+	if err != nil {
+		return nil, nil, err
+	}
+	if proof, err = tree.Lookup(code); err != nil {
+		return nil, nil, err
+	}
+	return proof, tree.Root(), nil
+}
+
+// CloseVoting stops accepting ballots, once the voting window is over
+// the root of the tree of every cast ballot is posted to the board, to commit to the list of ballots
+func (e *Election) CloseVoting() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("close voting", PhaseVotingOpen); err != nil {
+		return err
+	}
+	if now := e.Now(); now.Before(e.Manifest.VotingEnd) {
+		return fmt.Errorf("%w: voting ends at %s", ErrOutsideVotingWindow, e.Manifest.VotingEnd)
+	}
+	tree, err := NewBallotTree(e.ballots)
+	if err != nil {
+		return err
+	}
+	if err := e.publish(board.KindBallotRoot, &PublishedBallotRoot{Size: tree.Len(), Root: tree.Root()}); err != nil {
+		return err
+	}
+	e.phase = PhaseVotingClosed
+	return nil
+}
+
+// Mix passes the answers to each question through a cascade of the given mix servers
+// only the ballots that count are mixed, which with a voter registry is a single ballot per voter
+// if a server fails, the election stays in the mixing phase, nothing is posted, and mixing can be run again
+// a question answered by a single ballot is passed on unmixed, with no records: a shuffle needs at least 2 ballots,
+// and the count of a single ballot tells its answer anyway
+// returns the records of the mixnet, for each question
+func (e *Election) Mix(mixers ...Mixer) (records [][]*MixRecord, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("mix", PhaseVotingClosed, PhaseMixing); err != nil {
+		return nil, err
+	}
+	if len(mixers) == 0 {
+		return nil, errors.New("no mix servers given")
+	}
+	e.phase = PhaseMixing
+
+	records = make([][]*MixRecord, len(e.candidates))
+	mixed := make([][]*Ciphertext, len(e.candidates))
+	mixnet := NewMixnet(e.Suite, e.publicKey, mixers...)
+	ballots := e.countedBallots()
+	for i := range e.candidates {
+		input := make([]*Ciphertext, len(ballots))
+		for j, ballot := range ballots {
+			input[j] = ballot.Ciphertexts[i]
+		}
+		if len(input) < minShuffleLength {
+			mixed[i] = input // nothing to mix
+			continue
+		}
+		if mixed[i], records[i], err = mixnet.Run(input); err != nil {
+			return nil, fmt.Errorf("question %q: %w", e.Manifest.Questions[i].ID, err)
+		}
+	}
+
+	// the records are only posted once every question is mixed,
+	// so that a failed run leaves nothing on the board for the next one to repeat
+	for i, questionRecords := range records {
+		for _, record := range questionRecords {
+			if err := e.publish(board.KindMixRecord, &PublishedMixRecord{Question: e.Manifest.Questions[i].ID, Record: record}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	e.mixRecords = records
+	e.mixed = mixed
+	e.phase = PhaseDecrypting
+	return // records, nil
+}
+
+// MixRecords returns the records of the mixnet, for each question, once mixing is over
+func (e *Election) MixRecords() [][]*MixRecord {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.mixRecords
+}
+
+// Decrypt has the trustees decrypt the mixed answers, and counts the votes of each candidate
+// only the shares of the trustees that are online need to be given, as for DecryptMessages
+// every trustee's partial decryption of each question is kept, and once every question is counted,
+// posted to the board if the election has one, followed by the counts
+// if decryption fails, nothing is posted, and it can be run again with more shares
+// returns the indices of the trustees whose shadows failed verification
+func (e *Election) Decrypt(shares []*vss.DistKeyShare) (misbehaving []int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("decrypt", PhaseDecrypting); err != nil {
+		return nil, err
+	}
+
+	partials := make([][]*PartialDecryption, len(e.candidates))
+	counts := make([][]int, len(e.candidates))
+	faulty := make(map[int]bool)
+	for i, candidates := range e.candidates {
+		questionID := e.Manifest.Questions[i].ID
+		counts[i] = make([]int, len(candidates))
+		if len(e.mixed[i]) == 0 {
+			continue // nothing to decrypt
+		}
+
+		// each trustee could do this themselves, and publish the result
+		for _, distShare := range shares {
+			if distShare == nil {
+				continue // offline
+			}
+			partial, err := ExtractPartialDecryption(e.Suite, e.mixed[i], distShare)
+			if err != nil {
+				return nil, fmt.Errorf("question %q: %w", questionID, err)
+			}
+			partials[i] = append(partials[i], partial)
+		}
+
+		decrypted, questionMisbehaving, err := DecryptFromPartials(e.Suite, e.mixed[i], partials[i], e.commits, e.Manifest.Threshold, len(e.Manifest.Trustees))
+		for _, index := range questionMisbehaving {
+			faulty[index] = true
+		}
+		if err != nil {
+			return nil, fmt.Errorf("question %q: %w", questionID, err)
+		}
+
+		if counts[i], err = countAnswers(candidates, decrypted); err != nil {
+			return nil, fmt.Errorf("question %q: %w", questionID, err)
+		}
+	}
+
+	// the partial decryptions are only posted once every question is counted,
+	// so that decrypting again after a failure doesn't post them twice
+	for i, questionPartials := range partials {
+		for _, partial := range questionPartials {
+			if err := e.publish(board.KindPartialDecryption, &PublishedPartialDecryption{Question: e.Manifest.Questions[i].ID, Decryption: partial}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := e.publish(board.KindResult, &PublishedResult{Counts: counts}); err != nil {
+		return nil, err
+	}
+	e.partials = partials
+	e.counts = counts
+	e.misbehaving = sortedIndices(faulty)
+	e.phase = PhaseTallied
+	return e.misbehaving, nil
+}
+
+// PartialDecryptions returns the partial decryptions of the trustees, for each question, once decryption is over
+func (e *Election) PartialDecryptions() [][]*PartialDecryption {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.partials
+}
+
+// Results returns the number of votes of each candidate, for each question, in the order of the manifest
+func (e *Election) Results() (counts [][]int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.require("read the results", PhaseTallied); err != nil {
+		return nil, err
+	}
+	return e.counts, nil
+}
@@ -0,0 +1,107 @@
+package voting
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// helper functions to move points and scalars in and out of their binary encodings
+
+// ErrEmbedding is returned when data can't be embedded into a point, or read back out of one
+var ErrEmbedding = errors.New("embedding failed")
+
+// helper function, embeds data into a point
+// Embed silently drops whatever doesn't fit, so data longer than EmbedLen is refused here instead
+func embed(suite suites.Suite, data []byte, rand cipher.Stream) (kyber.Point, error) {
+	if len(data) > suite.Point().EmbedLen() {
+		return nil, fmt.Errorf("%w: %d bytes don't fit in a point, which holds %d", ErrEmbedding, len(data), suite.Point().EmbedLen())
+	}
+	return suite.Point().Embed(data, rand), nil
+}
+
+// helper function, decodes a point of the suite
+func decodePoint(suite suites.Suite, data []byte) (kyber.Point, error) {
+	point := suite.Point()
+	err := point.UnmarshalBinary(data)
+	return point, err
+}
+
+// helper function, decodes a scalar of the suite
+func decodeScalar(suite suites.Suite, data []byte) (kyber.Scalar, error) {
+	scalar := suite.Scalar()
+	err := scalar.UnmarshalBinary(data)
+	return scalar, err
+}
+
+// helper function, encodes a list of points
+func encodePoints(points []kyber.Point) (encoded [][]byte, err error) {
+	encoded = make([][]byte, len(points))
+	for i, point := range points {
+		if encoded[i], err = point.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return // encoded, nil
+}
+
+// helper function, decodes a list of points
+func decodePoints(suite suites.Suite, encoded [][]byte) (points []kyber.Point, err error) {
+	points = make([]kyber.Point, len(encoded))
+	for i, data := range encoded {
+		if points[i], err = decodePoint(suite, data); err != nil {
+			return nil, err
+		}
+	}
+	return // points, nil
+}
+
+// helper function, encodes a list of scalars
+func encodeScalars(scalars []kyber.Scalar) (encoded [][]byte, err error) {
+	encoded = make([][]byte, len(scalars))
+	for i, scalar := range scalars {
+		if encoded[i], err = scalar.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return // encoded, nil
+}
+
+// helper function, decodes a list of scalars
+func decodeScalars(suite suites.Suite, encoded [][]byte) (scalars []kyber.Scalar, err error) {
+	scalars = make([]kyber.Scalar, len(encoded))
+	for i, data := range encoded {
+		if scalars[i], err = decodeScalar(suite, data); err != nil {
+			return nil, err
+		}
+	}
+	return // scalars, nil
+}
+
+// helper function, derives the challenge of a non-interactive (Fiat-Shamir) proof
+// hashes the name of the proof and every part given, each prefixed by its length so parts can't run into each other,
+// and uses the hash to seed the pick of a scalar
+func hashChallenge(suite suites.Suite, domain string, parts ...[]byte) kyber.Scalar {
+	hash := suite.Hash()
+	length := make([]byte, 4)
+	for _, part := range append([][]byte{[]byte(domain)}, parts...) {
+		binary.BigEndian.PutUint32(length, uint32(len(part)))
+		hash.Write(length)
+		hash.Write(part)
+	}
+	return suite.Scalar().Pick(suite.XOF(hash.Sum(nil)))
+}
+
+// helper function, the binary encodings of a list of points, for hashing
+// points that can't be encoded are left empty, which makes any proof over them fail
+func pointBytes(points ...kyber.Point) (encoded [][]byte) {
+	encoded = make([][]byte, len(points))
+	for i, point := range points {
+		encoded[i], _ = point.MarshalBinary()
+	}
+	return // encoded
+}
@@ -0,0 +1,140 @@
+package voting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the name of the encryption proof, hashed into its challenge
+const encryptionProofDomain = "crypto-voting/encryption-proof"
+
+// ErrInvalidEncryptionProof is returned when a ballot doesn't prove knowledge of its encryption randomness
+var ErrInvalidEncryptionProof = errors.New("invalid proof of encryption")
+
+// EncryptionProof is a Schnorr proof of knowledge of the randomness y of an El Gamal message (g^y, Mg^(xy))
+// the proof is bound to the whole ciphertext, the election and the voter:
+// copying or re-encrypting another voter's ballot doesn't give its randomness, so a copy can't carry a valid proof
+type EncryptionProof struct {
+	Challenge kyber.Scalar // c = H(election, voter, g^y, Mg^(xy), g^k)
+	Response  kyber.Scalar // s = k + c*y
+}
+
+// ProveEncryption proves knowledge of the randomness an El Gamal message was encrypted with
+// given the ciphertext, its randomness (from EncryptMessageWithRandomness),
+// and the election and voter the proof is bound to
+func ProveEncryption(suite suites.Suite, ciphertext *Ciphertext, randomness kyber.Scalar, electionID, voterID string) *EncryptionProof {
+	nonce := suite.Scalar().Pick(suite.RandomStream()) // k
+	commitment := suite.Point().Mul(nonce, nil)        // g^k
+
+	challenge := encryptionChallenge(suite, ciphertext, commitment, electionID, voterID)
+	response := suite.Scalar().Mul(challenge, randomness) // c*y
+	response.Add(response, nonce)                         // k + c*y
+
+	return &EncryptionProof{Challenge: challenge, Response: response}
+}
+
+// VerifyEncryption checks a proof of knowledge of the randomness of an El Gamal message
+// for the given election and voter
+func VerifyEncryption(suite suites.Suite, ciphertext *Ciphertext, proof *EncryptionProof, electionID, voterID string) error {
+	if proof == nil || proof.Challenge == nil || proof.Response == nil {
+		return fmt.Errorf("%w: proof is missing", ErrInvalidEncryptionProof)
+	}
+	if ciphertext == nil || ciphertext.C1 == nil || ciphertext.C2 == nil {
+		return fmt.Errorf("%w: ciphertext is missing", ErrInvalidEncryptionProof)
+	}
+
+	// recover the commitment: g^s / (g^y)^c == g^(k + c*y - c*y) == g^k
+	commitment := suite.Point().Mul(proof.Response, nil)
+	commitment.Sub(commitment, suite.Point().Mul(proof.Challenge, ciphertext.C1))
+
+	// the challenge only matches if the commitment was picked before it, for this very ciphertext and voter
+	if !encryptionChallenge(suite, ciphertext, commitment, electionID, voterID).Equal(proof.Challenge) {
+		return ErrInvalidEncryptionProof
+	}
+	return nil
+}
+
+// the challenge of an encryption proof
+func encryptionChallenge(suite suites.Suite, ciphertext *Ciphertext, commitment kyber.Point, electionID, voterID string) kyber.Scalar {
+	parts := append([][]byte{[]byte(electionID), []byte(voterID)}, pointBytes(ciphertext.C1, ciphertext.C2, commitment)...)
+	return hashChallenge(suite, encryptionProofDomain, parts...)
+}
+
+// MarshalBinary encodes the proof as the binary encoding of the challenge followed by that of the response
+func (p *EncryptionProof) MarshalBinary() ([]byte, error) {
+	if p == nil || p.Challenge == nil || p.Response == nil {
+		return nil, errors.New("proof of encryption is missing")
+	}
+	challenge, err := p.Challenge.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	response, err := p.Response.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(challenge, response...), nil
+}
+
+// DecodeEncryptionProof decodes a proof encoded by MarshalBinary, with scalars of the given suite
+func DecodeEncryptionProof(suite suites.Suite, data []byte) (p *EncryptionProof, err error) {
+	scalarLen := suite.ScalarLen()
+	if len(data) != 2*scalarLen {
+		return nil, fmt.Errorf("encryption proof is %d bytes long, expected %d", len(data), 2*scalarLen)
+	}
+	p = &EncryptionProof{}
+	if p.Challenge, err = decodeScalar(suite, data[:scalarLen]); err != nil {
+		return nil, err
+	}
+	if p.Response, err = decodeScalar(suite, data[scalarLen:]); err != nil {
+		return nil, err
+	}
+	return // p, nil
+}
+
+// the JSON form of a proof, with both scalars hex encoded
+type jsonEncryptionProof struct {
+	Challenge string
+	Response  string
+}
+
+// MarshalJSON encodes the proof as a JSON object with both scalars hex encoded
+func (p *EncryptionProof) MarshalJSON() ([]byte, error) {
+	challenge, err := p.Challenge.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	response, err := p.Response.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonEncryptionProof{Challenge: hex.EncodeToString(challenge), Response: hex.EncodeToString(response)})
+}
+
+// decodes the JSON form of a proof, with scalars of the given suite
+func (encoded *jsonEncryptionProof) decode(suite suites.Suite) (p *EncryptionProof, err error) {
+	if encoded == nil {
+		return nil, fmt.Errorf("%w: proof is missing", ErrInvalidEncryptionProof)
+	}
+	challenge, err := hex.DecodeString(encoded.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	response, err := hex.DecodeString(encoded.Response)
+	if err != nil {
+		return nil, err
+	}
+	p = &EncryptionProof{}
+	if p.Challenge, err = decodeScalar(suite, challenge); err != nil {
+		return nil, err
+	}
+	if p.Response, err = decodeScalar(suite, response); err != nil {
+		return nil, err
+	}
+	return // p, nil
+}
@@ -0,0 +1,188 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"go.dedis.ch/kyber/v3"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the homomorphic tally is an alternative to mixing:
+// each ballot holds one exponential El Gamal ciphertext per candidate, (g^y, g^v h^y), with v = 1 for the chosen candidate and 0 otherwise
+// multiplying ciphertexts adds up their exponents, so the ballots can be summed per candidate without decrypting any of them
+// only the totals are decrypted, and the counts are recovered from g^count by solving a (small) discrete log
+
+// ErrCountOutOfRange is returned when a decrypted total isn't g^v for any v in the expected range
+var ErrCountOutOfRange = errors.New("decrypted total is out of range")
+
+// EncodeCount encodes a number v as the point g^v, as used by exponential El Gamal
+func EncodeCount(suite suites.Suite, v int64) kyber.Point {
+	return suite.Point().Mul(suite.Scalar().SetInt64(v), nil)
+}
+
+// the values a single ciphertext of a homomorphic ballot may encrypt: g^0 and g^1
+func voteEncodings(suite suites.Suite) []kyber.Point {
+	return []kyber.Point{EncodeCount(suite, 0), EncodeCount(suite, 1)}
+}
+
+// EncryptVote encrypts a vote for one of candidateCount candidates as a homomorphic ballot
+// the ballot holds one ciphertext per candidate, encrypting g^1 for the chosen candidate and g^0 for the others
+// every ciphertext proves it encrypts g^0 or g^1, and the ballot proves that they add up to g^1,
+// so no voter can vote more than once, or with a negative weight
+func EncryptVote(suite suites.Suite, choice, candidateCount int, pubKey kyber.Point, electionID, voterID string) (*Ballot, error) {
+	if choice < 0 || choice >= candidateCount {
+		return nil, fmt.Errorf("choice %d is not one of the %d candidates", choice, candidateCount)
+	}
+
+	ballot := &Ballot{
+		Suite:        suite.String(),
+		ElectionID:   electionID,
+		VoterID:      voterID,
+		Ciphertexts:  make([]*Ciphertext, candidateCount),
+		Proofs:       make([]*EncryptionProof, candidateCount),
+		ChoiceProofs: make([]*ChoiceProof, candidateCount),
+	}
+	encodings := voteEncodings(suite)
+	totalRandomness := suite.Scalar().Zero() // the randomness of the sum of the ciphertexts
+	for i := range ballot.Ciphertexts {
+		value := 0 // g^0 for the candidates that weren't chosen
+		if i == choice {
+			value = 1 // g^1 for the chosen one
+		}
+		elGamal1, elGamal2, randomness := EncryptMessageWithRandomness(suite, encodings[value], pubKey)
+		ballot.Ciphertexts[i] = &Ciphertext{C1: elGamal1, C2: elGamal2}
+		ballot.Proofs[i] = ProveEncryption(suite, ballot.Ciphertexts[i], randomness, electionID, voterID)
+		proof, err := ProveChoice(suite, ballot.Ciphertexts[i], randomness, value, encodings, pubKey, electionID, voterID)
+		if err != nil {
+			return nil, err
+		}
+		ballot.ChoiceProofs[i] = proof
+		totalRandomness.Add(totalRandomness, randomness)
+	}
+
+	// the ciphertexts add up to g^1, encrypted with the sum of their randomness
+	total := SumCiphertexts(suite, ballot.Ciphertexts)
+	sumProof, err := ProveChoice(suite, total, totalRandomness, 0, []kyber.Point{encodings[1]}, pubKey, electionID, voterID)
+	if err != nil {
+		return nil, err
+	}
+	ballot.SumProof = sumProof
+
+	return ballot, nil
+}
+
+// VerifyVote checks that the ballot is a valid homomorphic vote for one of candidateCount candidates
+// this is on top of Verify
+func (b *Ballot) VerifyVote(suite suites.Suite, candidateCount int, pubKey kyber.Point) error {
+	if len(b.Ciphertexts) != candidateCount {
+		return fmt.Errorf("ballot holds %d ciphertexts for %d candidates", len(b.Ciphertexts), candidateCount)
+	}
+	encodings := voteEncodings(suite)
+	if err := b.VerifyChoices(suite, encodings, pubKey); err != nil {
+		return err
+	}
+	if b.SumProof == nil {
+		return fmt.Errorf("%w: sum proof is missing", ErrInvalidChoiceProof)
+	}
+	total := SumCiphertexts(suite, b.Ciphertexts)
+	if err := VerifyChoice(suite, total, b.SumProof, []kyber.Point{encodings[1]}, pubKey, b.ElectionID, b.VoterID); err != nil {
+		return fmt.Errorf("ballot doesn't hold exactly one vote: %w", err)
+	}
+	return nil
+}
+
+// IngestVoteBallots checks every ballot of a homomorphic election before it is tallied
+// on top of the checks of IngestBallots, ballots that aren't a valid vote for one of the candidates are rejected
+func IngestVoteBallots(suite suites.Suite, electionID string, candidateCount int, pubKey kyber.Point, ballots []*Ballot) (accepted []*Ballot, rejected map[int]error) {
+	indices, rejected := ingest(suite, electionID, ballots, func(ballot *Ballot) error {
+		return ballot.VerifyVote(suite, candidateCount, pubKey)
+	})
+	return pickBallots(ballots, indices), rejected
+}
+
+// SumCiphertexts multiplies El Gamal messages together, which adds up the exponents they encrypt
+// (g^y1, g^v1 h^y1) * (g^y2, g^v2 h^y2) == (g^(y1+y2), g^(v1+v2) h^(y1+y2))
+func SumCiphertexts(suite suites.Suite, ciphertexts []*Ciphertext) *Ciphertext {
+	total := &Ciphertext{C1: suite.Point().Null(), C2: suite.Point().Null()}
+	for _, ciphertext := range ciphertexts {
+		total.C1.Add(total.C1, ciphertext.C1)
+		total.C2.Add(total.C2, ciphertext.C2)
+	}
+	return total
+}
+
+// AggregateVotes sums the homomorphic ballots per candidate
+// returns one ciphertext per candidate, encrypting g^count
+func AggregateVotes(suite suites.Suite, ballots []*Ballot, candidateCount int) (totals []*Ciphertext, err error) {
+	perCandidate := make([][]*Ciphertext, candidateCount) // the ciphertexts of each candidate, across all ballots
+	for i, ballot := range ballots {
+		if len(ballot.Ciphertexts) != candidateCount {
+			return nil, fmt.Errorf("ballot %d holds %d ciphertexts for %d candidates", i, len(ballot.Ciphertexts), candidateCount)
+		}
+		for j, ciphertext := range ballot.Ciphertexts {
+			perCandidate[j] = append(perCandidate[j], ciphertext)
+		}
+	}
+
+	totals = make([]*Ciphertext, candidateCount)
+	for j := range totals {
+		totals[j] = SumCiphertexts(suite, perCandidate[j])
+	}
+	return // totals, nil
+}
+
+// DecryptTally threshold decrypts the totals of a homomorphic election, and recovers the count of each candidate
+// the totals are decrypted like any other message, with the shadows of the trustees,
+// and each count is then found by solving a discrete log bounded by maxCount (usually the number of ballots)
+// returns the counts, in order of candidate, and the indices of the trustees that misbehaved
+func DecryptTally(suite suites.Suite, totals []*Ciphertext, shares []*vss.DistKeyShare, threshold, contributorCount int, maxCount int64) (counts []int64, misbehaving []int, err error) {
+	elGamal1, elGamal2, err := SplitCiphertexts(totals)
+	if err != nil {
+		return nil, nil, err
+	}
+	decrypted, misbehaving, err := DecryptMessages(suite, elGamal1, elGamal2, shares, threshold, contributorCount)
+	if err != nil {
+		return nil, misbehaving, err
+	}
+
+	counts = make([]int64, len(decrypted))
+	for j, point := range decrypted {
+		if counts[j], err = SolveDiscreteLog(suite, point, maxCount); err != nil {
+			return nil, misbehaving, fmt.Errorf("candidate %d: %w", j, err)
+		}
+	}
+	return // counts, misbehaving, nil
+}
+
+// SolveDiscreteLog finds v in [0, max] such that point == g^v
+// uses baby-step giant-step, which takes about sqrt(max) time and memory
+// returns ErrCountOutOfRange if there is no such v
+func SolveDiscreteLog(suite suites.Suite, point kyber.Point, max int64) (int64, error) {
+	if max < 0 {
+		return 0, ErrCountOutOfRange
+	}
+	m := int64(math.Ceil(math.Sqrt(float64(max) + 1))) // the size of a giant step
+
+	// baby steps: remember g^j for every j in [0, m)
+	babySteps := make(map[string]int64, m)
+	step := suite.Point().Null()
+	base := suite.Point().Base()
+	for j := int64(0); j < m; j++ {
+		babySteps[step.String()] = j
+		step = suite.Point().Add(step, base)
+	}
+
+	// giant steps: look for point / g^(i*m) among the baby steps
+	giantStep := suite.Point().Neg(suite.Point().Mul(suite.Scalar().SetInt64(m), nil)) // g^(-m)
+	current := suite.Point().Set(point)
+	for i := int64(0); i*m <= max; i++ {
+		if j, ok := babySteps[current.String()]; ok && i*m+j <= max {
+			return i*m + j, nil
+		}
+		current.Add(current, giantStep)
+	}
+	return 0, ErrCountOutOfRange
+}
@@ -0,0 +1,117 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// ErrDuplicateCiphertext is returned for a ballot holding a ciphertext that was already cast
+var ErrDuplicateCiphertext = errors.New("ciphertext was already cast")
+
+// IngestBallots checks every ballot before it is mixed
+// ballots cast in another election or with another suite, ballots whose proofs of encryption don't hold,
+// and ballots holding a ciphertext that was already accepted are rejected
+// returns the accepted ballots, in order, and the reason each rejected ballot (by index) was turned away
+func IngestBallots(suite suites.Suite, electionID string, ballots []*Ballot) (accepted []*Ballot, rejected map[int]error) {
+	indices, rejected := ingest(suite, electionID, ballots, nil)
+	return pickBallots(ballots, indices), rejected
+}
+
+// IngestChoiceBallots checks every ballot of a candidate-list election before it is mixed
+// on top of the checks of IngestBallots, ballots that don't prove they encrypt one of the candidates are rejected
+func IngestChoiceBallots(suite suites.Suite, electionID string, candidates []kyber.Point, pubKey kyber.Point, ballots []*Ballot) (accepted []*Ballot, rejected map[int]error) {
+	indices, rejected := ingest(suite, electionID, ballots, func(ballot *Ballot) error {
+		return ballot.VerifyChoices(suite, candidates, pubKey)
+	})
+	return pickBallots(ballots, indices), rejected
+}
+
+// IngestSignedBallots checks every ballot of an election with a voter registry before it is mixed
+// on top of the checks of IngestBallots, ballots from unregistered voters and ballots without a valid signature
+// of their voter are rejected, and the re-voting policy of the registry picks the one ballot that counts for each voter
+// the extra check, if not nil, is run on every ballot as well, e.g. Ballot.VerifyChoices
+func IngestSignedBallots(suite suites.Suite, registry *Registry, ballots []*Ballot, extra func(*Ballot) error) (accepted []*Ballot, rejected map[int]error) {
+	indices, rejected := ingest(suite, registry.ElectionID, ballots, func(ballot *Ballot) error {
+		if err := registry.VerifyBallot(suite, ballot); err != nil {
+			return err
+		}
+		if extra != nil {
+			return extra(ballot)
+		}
+		return nil
+	})
+	counted, superseded := applyRevotePolicy(ballots, indices, registry.Policy)
+	for i, err := range superseded {
+		rejected[i] = err
+	}
+	return pickBallots(ballots, counted), rejected
+}
+
+// checks every ballot, running the extra check (if any) on the ballots that pass the common ones
+// returns the indices of the accepted ballots, in order
+func ingest(suite suites.Suite, electionID string, ballots []*Ballot, extra func(*Ballot) error) (accepted []int, rejected map[int]error) {
+	accepted = make([]int, 0, len(ballots))
+	rejected = make(map[int]error)
+	seen := make(map[string]bool) // the first halves of the ciphertexts accepted so far
+
+	for i, ballot := range ballots {
+		if err := ballot.Verify(suite, electionID); err != nil {
+			rejected[i] = err
+			continue
+		}
+		if extra != nil {
+			if err := extra(ballot); err != nil {
+				rejected[i] = err
+				continue
+			}
+		}
+
+		// a ballot copied as is carries a valid proof, but its ciphertexts were already cast
+		keys, err := freshCiphertexts(seen, ballot)
+		if err != nil {
+			rejected[i] = err
+			continue
+		}
+
+		for key := range keys {
+			seen[key] = true
+		}
+		accepted = append(accepted, i)
+	}
+	return // accepted, rejected
+}
+
+// helper function, the ballots at the given indices, in order
+func pickBallots(ballots []*Ballot, indices []int) []*Ballot {
+	picked := make([]*Ballot, len(indices))
+	for j, i := range indices {
+		picked[j] = ballots[i]
+	}
+	return picked
+}
+
+// helper function, checks that none of the ciphertexts of a ballot were seen before, nor repeat within the ballot
+// returns the keys of the ballot's ciphertexts, to be added to seen once the ballot is accepted
+func freshCiphertexts(seen map[string]bool, ballot *Ballot) (keys map[string]bool, err error) {
+	keys = make(map[string]bool, len(ballot.Ciphertexts))
+	for j, ciphertext := range ballot.Ciphertexts {
+		key := ciphertext.C1.String()
+		if seen[key] || keys[key] {
+			return nil, fmt.Errorf("ciphertext %d: %w", j, ErrDuplicateCiphertext)
+		}
+		keys[key] = true
+	}
+	return // keys, nil
+}
+
+// BallotCiphertexts lists the ciphertexts of every ballot as two halves, ready for ShuffleAndCheck
+func BallotCiphertexts(ballots []*Ballot) (elGamal1, elGamal2 []kyber.Point, err error) {
+	ciphertexts := make([]*Ciphertext, 0, len(ballots))
+	for _, ballot := range ballots {
+		ciphertexts = append(ciphertexts, ballot.Ciphertexts...)
+	}
+	return SplitCiphertexts(ciphertexts)
+}
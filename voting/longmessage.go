@@ -0,0 +1,291 @@
+package voting
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// hyper-parameters
+// messagePartitions is the election-wide number of chunks of a long message, so every long ballot looks alike
+// it was 8 before every message carried its header and tag (19 bytes): an ed25519 chunk holds 12 bytes of data,
+// so 8 chunks would leave 77 bytes for the message, short of the longest sample message (84 bytes), and 10 leave 101
+var messagePartitions = 10
+var randomnessLength = 16
+
+// the layout of the data carried by the chunks of a long message:
+// [length: 2 bytes][chunk count - 1: 1 byte][message][tag][zero padding]
+const longMessageHeaderLength = 3
+const longMessageTagLength = 16 // a truncated sha256, over the prefix, the header and the message
+const longMessageTagDomain = "crypto-voting/long-message"
+
+// the most chunks a long message can be split into, as the position of a chunk is a single byte
+const maxMessageChunks = 256
+
+// ErrMessageTooLong is returned when a message doesn't fit in the chunks allowed for it
+var ErrMessageTooLong = errors.New("message too long")
+
+// ErrCorruptMessage is returned when the chunks of a long message don't hold the whole message it was encrypted from:
+// chunks are missing, the message is truncated, or it has been tampered with
+var ErrCorruptMessage = errors.New("corrupt long message")
+
+// LongMessageChunks returns the number of chunks needed to hold a message of length bytes, along with its header and tag
+func LongMessageChunks(suite suites.Suite, length int) int {
+	meaningfulDataLength := suite.Point().EmbedLen() - randomnessLength - 1
+	return (longMessageHeaderLength + length + longMessageTagLength + meaningfulDataLength - 1) / meaningfulDataLength
+}
+
+// EncryptLongMessage splits data into messagePartitions chunks, embeds and encrypts each of them
+// every long message takes the same number of chunks, so they can be shuffled together as vectors,
+// and the number of chunks doesn't tell the length of the message
+// returns an error wrapping ErrMessageTooLong if the data doesn't fit in messagePartitions chunks
+func EncryptLongMessage(suite suites.Suite, data []byte, h kyber.Point) (messagePortions, elGamal1, elGamal2 []kyber.Point, err error) {
+	return EncryptLongMessageChunks(suite, data, h, messagePartitions)
+}
+
+// EncryptLongMessageChunks splits data into the given number of chunks, embeds and encrypts each of them
+// every chunk is prefixed by the same random bytes and its position in the message,
+// which lets CompileMessages put the message back together after a shuffle
+// the chunks hold the length of the message and an integrity tag besides the data, see CompileVector
+// chunks of 0 or less uses just as many chunks as the message needs, see LongMessageChunks
+// returns an error wrapping ErrMessageTooLong if the data doesn't fit
+func EncryptLongMessageChunks(suite suites.Suite, data []byte, h kyber.Point, chunks int) (messagePortions, elGamal1, elGamal2 []kyber.Point, err error) {
+	buffers, err := encodeLongMessage(suite, data, chunks)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	messagePortions = make([]kyber.Point, len(buffers))
+	elGamal1 = make([]kyber.Point, len(buffers))
+	elGamal2 = make([]kyber.Point, len(buffers))
+
+	// encrypt each message portion
+	for i, buffer := range buffers {
+		embeddedMessage, err := embed(suite, buffer, suite.RandomStream()) // embed the message portion
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		messagePortions[i] = embeddedMessage                                 // record the message portion embedding
+		elGamal1[i], elGamal2[i] = EncryptMessage(suite, embeddedMessage, h) // encrypt the message portion
+	}
+
+	return // messagePortions, elGamal1, elGamal2, nil
+}
+
+// helper function, lays a message out in chunks, each ready to be embedded in a point: [random prefix][position][data]
+// the data of all the chunks, put together, is the header, the message, the tag, then zero padding
+func encodeLongMessage(suite suites.Suite, data []byte, chunks int) (buffers [][]byte, err error) {
+	meaningfulDataLength := suite.Point().EmbedLen() - randomnessLength - 1 // the length of meaningful data: length available - randomness length - 1 postional byte
+	needed := LongMessageChunks(suite, len(data))
+
+	if chunks > maxMessageChunks {
+		return nil, fmt.Errorf("a long message can't have %d chunks, the most is %d", chunks, maxMessageChunks)
+	}
+	if chunks <= 0 {
+		chunks = min(needed, maxMessageChunks)
+	}
+	if needed > chunks || len(data) > 0xFFFF {
+		return nil, fmt.Errorf("%w: message of %d bytes needs %d chunks of %d bytes, %d allowed", ErrMessageTooLong, len(data), needed, meaningfulDataLength, chunks)
+	}
+
+	// create a blank byte array to XOR with randomness
+	// the randomness is shared by every chunk of the message
+	prefix := make([]byte, randomnessLength)
+	suite.RandomStream().XORKeyStream(prefix, prefix)
+
+	// the data of every chunk, one after the other, zero padded
+	stream := make([]byte, chunks*meaningfulDataLength)
+	binary.BigEndian.PutUint16(stream, uint16(len(data)))
+	stream[2] = byte(chunks - 1)
+	copy(stream[longMessageHeaderLength:], data)
+	end := longMessageHeaderLength + len(data)
+	copy(stream[end:], longMessageTag(prefix, stream[:end]))
+
+	// split the message into parts
+	buffers = make([][]byte, chunks)
+	for i := range buffers {
+		buffer := make([]byte, suite.Point().EmbedLen())                                             // a buffer to hold the message portion
+		copy(buffer[0:randomnessLength], prefix)                                                     // transfer the randomness to the buffer
+		buffer[randomnessLength] = byte(i)                                                           // index of this message portion
+		copy(buffer[randomnessLength+1:], stream[i*meaningfulDataLength:(i+1)*meaningfulDataLength]) // this portion's share of the data
+		buffers[i] = buffer
+	}
+	return // buffers, nil
+}
+
+// helper function, the integrity tag of a long message, bound to its random prefix
+func longMessageTag(prefix, body []byte) []byte {
+	hash := sha256.New()
+	hash.Write([]byte(longMessageTagDomain))
+	hash.Write(prefix)
+	hash.Write(body)
+	return hash.Sum(nil)[:longMessageTagLength]
+}
+
+// helper function, reads a message back from its chunks, given in order, as laid out by encodeLongMessage
+// returns an error wrapping ErrEmbedding if a chunk is malformed, belongs to another message or sits out of place,
+// and ErrCorruptMessage if the chunks don't hold the whole message, untampered
+func decodeLongMessage(chunks [][]byte) (data []byte, err error) {
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: no message portions", ErrCorruptMessage)
+	}
+	var prefix []byte
+	stream := make([]byte, 0)
+	for i, chunk := range chunks {
+		if len(chunk) <= randomnessLength { // too short for the prefix and the positional byte
+			return nil, fmt.Errorf("%w: message portion %d holds only %d bytes", ErrEmbedding, i, len(chunk))
+		}
+		if i == 0 {
+			prefix = chunk[0:randomnessLength]
+		} else if !isDataEqual(prefix, chunk[0:randomnessLength]) {
+			return nil, fmt.Errorf("%w: message portion %d belongs to another message", ErrEmbedding, i)
+		}
+		if int(chunk[randomnessLength]) != i {
+			return nil, fmt.Errorf("%w: message portion %d claims position %d", ErrEmbedding, i, chunk[randomnessLength])
+		}
+		stream = append(stream, chunk[randomnessLength+1:]...)
+	}
+
+	if len(stream) < longMessageHeaderLength {
+		return nil, fmt.Errorf("%w: %d bytes is too short for the header", ErrCorruptMessage, len(stream))
+	}
+	length := int(binary.BigEndian.Uint16(stream))
+	chunkCount := int(stream[2]) + 1
+	if chunkCount != len(chunks) {
+		return nil, fmt.Errorf("%w: message was split into %d chunks, %d given", ErrCorruptMessage, chunkCount, len(chunks))
+	}
+	end := longMessageHeaderLength + length
+	if len(stream) < end+longMessageTagLength {
+		return nil, fmt.Errorf("%w: message of %d bytes is truncated to %d", ErrCorruptMessage, length, len(stream)-longMessageHeaderLength)
+	}
+	if !isDataEqual(stream[end:end+longMessageTagLength], longMessageTag(prefix, stream[:end])) {
+		return nil, fmt.Errorf("%w: integrity tag doesn't match", ErrCorruptMessage)
+	}
+	for _, b := range stream[end+longMessageTagLength:] {
+		if b != 0 {
+			return nil, fmt.Errorf("%w: padding isn't blank", ErrCorruptMessage)
+		}
+	}
+	return stream[longMessageHeaderLength:end], nil
+}
+
+// the default, sample messages
+var defaultLongMessages = []string{
+	"Hello. My name is BOB. I vote for Smith.",
+	"Bonjour. My name is ALBERT. I vote for QUEEN.",
+	"Hi. My name is ALSO BOB. I vote for NIL.",
+	"HMMM... I pass.",
+	"This is an example message. As you can see, these messages can be quite long indeed!",
+}
+
+// helper function, the i-th sample long message
+func sampleLongMessage(i int) []byte {
+	if i < len(defaultLongMessages) { // use a manually created message
+		return []byte(defaultLongMessages[i])
+	}
+	return []byte("This is Sample Long Message #" + strconv.Itoa(i)) // use a generated sample message
+}
+
+// GenerateLongMessageEncryptions generates n long messages alongside their encryptions
+// each message takes up messagePartitions consecutive el gamal pairs
+// the pairs are shuffled one by one, see GenerateLongMessageVectors to keep the pairs of a message together
+// the messages are free text, see GenerateSelectionVectors for typed ballots that can be tallied
+func GenerateLongMessageEncryptions(suite suites.Suite, n int, h kyber.Point) (messages, elGamal1, elGamal2 []kyber.Point, err error) {
+
+	// these three slices are given at size 0, as we will append to them later on
+	messages = make([]kyber.Point, 0) // the el gamal messages
+	elGamal1 = make([]kyber.Point, 0) // the el gamal pairs
+	elGamal2 = make([]kyber.Point, 0) // the el gamal pairs
+
+	// initialize the elGamal pairs
+	// // pick random messages
+	// pick meaningful messages
+	// and encrypt them with the threshold public key
+	for i := 0; i < n; i++ {
+		messagesToAdd, elGamal1ToAdd, elGamal2ToAdd, err := EncryptLongMessage(suite, sampleLongMessage(i), h) // encrypt the long message
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		messages = append(messages, messagesToAdd...) // add messages
+		elGamal1 = append(elGamal1, elGamal1ToAdd...) // add elGamal1
+		elGamal2 = append(elGamal2, elGamal2ToAdd...) // add elGamal2
+
+	}
+
+	return // messages, elGamal1, elGamal2, nil
+
+}
+
+// SortableBytesList is a wrapper for a double array of bytes, used to sort
+type SortableBytesList [][]byte
+
+// simple minimum function
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// functions required for sorting
+func (a SortableBytesList) Len() int      { return len(a) }
+func (a SortableBytesList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a SortableBytesList) Less(i, j int) bool {
+	for k := 0; k < min(len(a[i]), len(a[j])); k++ { // loop through the byte array to see the first byte that differs
+		if a[i][k] != a[j][k] { // if this byte differs...
+			return a[i][k] < a[j][k] // it is either greater or less than the other byte
+		}
+	}
+	return len(a[i]) < len(a[j]) // no bytes differed
+}
+
+// helper function to tell whether two arrays of bytes have the same data
+func isDataEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false // the length of the slices to not match
+	}
+	for i := range a { // the arrays have the same length, so we can index over either
+		if a[i] != b[i] { // this byte is different
+			return false
+		}
+	}
+	// everything matched
+	return true
+}
+
+// CompileMessages combines all decrypted message portions into individual long messages, in the order of their random prefixes
+// each message is returned exactly as it was encrypted, without the padding of its last chunks
+// copies of a chunk are merged; any other problem fails, see ReassembleMessages for a report on every ballot instead
+// returns an error wrapping ErrEmbedding if any of the portions doesn't hold a message chunk,
+// ErrCorruptMessage if a message is missing chunks or has been tampered with,
+// and ErrConflictingChunks if two different chunks claim the same place in a message
+func CompileMessages(decryptedMessages []kyber.Point) (completeMessages []string, err error) {
+	report, err := ReassembleMessages(decryptedMessages, StrictReassemblyPolicy)
+	if err != nil {
+		return nil, err
+	}
+	return report.Messages, nil
+}
+
+// CompileVector puts a long message back together from the decrypted portions of its vector, in order
+// every portion must carry the same random prefix, and its own position in the vector
+// returns an error wrapping ErrEmbedding if any portion doesn't hold the chunk expected there,
+// and ErrCorruptMessage if chunks are missing, or the message doesn't match its length or integrity tag
+func CompileVector(decryptedPortions []kyber.Point) (completeMessage string, err error) {
+	chunks := make([][]byte, len(decryptedPortions))
+	for i, portion := range decryptedPortions {
+		if chunks[i], err = portion.Data(); err != nil { // retrieve the data from the point
+			return "", fmt.Errorf("%w: message portion %d: %v", ErrEmbedding, i, err)
+		}
+	}
+	data, err := decodeLongMessage(chunks)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
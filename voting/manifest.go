@@ -0,0 +1,111 @@
+package voting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrInvalidManifest is returned for a manifest that doesn't describe a runnable election
+var ErrInvalidManifest = errors.New("invalid election manifest")
+
+// Manifest defines an election: everything that is fixed before the key ceremony starts
+// it is public, and every trustee, voter and verifier works from the same copy
+type Manifest struct {
+	ElectionID  string      // the election, bound into every ballot and proof
+	Suite       string      // the name of the cipher suite of the election
+	Questions   []*Question // the questions on the ballot, in order
+	Trustees    []string    // the names of the trustees holding shares of the election key, in order of index
+	Threshold   int         // the number of trustees needed to decrypt
+	VotingStart time.Time   // voting can't open before this time
+	VotingEnd   time.Time   // voting can't close before this time, and no ballot is cast after it
+}
+
+// Question is a question on the ballot, answered by picking one of its candidates
+type Question struct {
+	ID         string   // a short identifier of the question, unique within the election
+	Text       string   // the question, as shown to the voters
+	Candidates []string // the names of the candidates, in order
+}
+
+// Validate checks that the manifest describes an election that can run
+// the suite is only checked by name here, NewElection makes sure it can hold ballots
+func (m *Manifest) Validate() error {
+	if m.ElectionID == "" {
+		return fmt.Errorf("%w: no election ID", ErrInvalidManifest)
+	}
+	if m.Suite == "" {
+		return fmt.Errorf("%w: no cipher suite", ErrInvalidManifest)
+	}
+	if len(m.Questions) == 0 {
+		return fmt.Errorf("%w: no questions", ErrInvalidManifest)
+	}
+	questionIDs := make(map[string]bool, len(m.Questions))
+	for i, question := range m.Questions {
+		if question == nil || question.ID == "" {
+			return fmt.Errorf("%w: question %d has no ID", ErrInvalidManifest, i)
+		}
+		if questionIDs[question.ID] {
+			return fmt.Errorf("%w: question ID %q is used twice", ErrInvalidManifest, question.ID)
+		}
+		questionIDs[question.ID] = true
+		if err := checkNames(question.Candidates); err != nil {
+			return fmt.Errorf("%w: candidates of question %q: %v", ErrInvalidManifest, question.ID, err)
+		}
+	}
+	if err := checkNames(m.Trustees); err != nil {
+		return fmt.Errorf("%w: trustees: %v", ErrInvalidManifest, err)
+	}
+	if m.Threshold < 1 || m.Threshold > len(m.Trustees) {
+		return fmt.Errorf("%w: threshold %d with %d trustees", ErrInvalidManifest, m.Threshold, len(m.Trustees))
+	}
+	if !m.VotingStart.Before(m.VotingEnd) {
+		return fmt.Errorf("%w: voting ends at %s, before it starts at %s", ErrInvalidManifest, m.VotingEnd, m.VotingStart)
+	}
+	return nil
+}
+
+// helper function, checks that a list of names isn't empty, and that every name is set and unique
+func checkNames(names []string) error {
+	if len(names) == 0 {
+		return errors.New("none given")
+	}
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		if name == "" {
+			return fmt.Errorf("name %d is empty", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("%q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// SaveManifest writes a manifest to a file, in its JSON form
+func SaveManifest(path string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadManifest reads and validates a manifest written by SaveManifest
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("%s is not a manifest: %v", path, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
@@ -0,0 +1,242 @@
+package voting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// ErrBrokenMixChain is returned when the records of a mixnet don't link up into a chain
+var ErrBrokenMixChain = errors.New("broken mix chain")
+
+// MixRecord is what a mix server publishes after its shuffle:
+// the list it was given, the list it produced, and the proof that one is a shuffle of the other
+// the record holds everything needed to check the shuffle, so it can be saved and audited offline
+type MixRecord struct {
+	Suite     string        // the name of the cipher suite the ciphertexts belong to
+	Server    string        // the name of the mix server
+	PublicKey kyber.Point   // the public key the ciphertexts are encrypted with
+	Input     []*Ciphertext // the ciphertexts the server was given
+	Output    []*Ciphertext // the re-encrypted and permuted ciphertexts
+	Proof     []byte        // the proof of the shuffle
+}
+
+// Verify checks the proof of the shuffle in the record, which must belong to the given suite
+// only the record itself is needed, so this can be done by anyone, at any time
+func (r *MixRecord) Verify(suite suites.Suite) error {
+	if err := checkSuite(suite, r.Suite, "mix record"); err != nil {
+		return err
+	}
+	if r.PublicKey == nil {
+		return fmt.Errorf("%w: mix record has no public key", ErrInvalidShuffleProof)
+	}
+	if len(r.Input) < minShuffleLength || len(r.Output) != len(r.Input) {
+		return fmt.Errorf("%w: %d ciphertexts shuffled into %d, a shuffle takes at least %d", ErrInvalidShuffleProof, len(r.Input), len(r.Output), minShuffleLength)
+	}
+	elGamal1, elGamal2, err := SplitCiphertexts(r.Input)
+	if err != nil {
+		return fmt.Errorf("%w: input: %v", ErrInvalidShuffleProof, err)
+	}
+	shuffledElGamal1, shuffledElGamal2, err := SplitCiphertexts(r.Output)
+	if err != nil {
+		return fmt.Errorf("%w: output: %v", ErrInvalidShuffleProof, err)
+	}
+	return VerifyShuffle(suite, r.PublicKey, elGamal1, elGamal2, shuffledElGamal1, shuffledElGamal2, r.Proof)
+}
+
+// the JSON form of a record, with the public key hex encoded
+// the ciphertexts have their own JSON form, and the proof is base64 encoded
+type jsonMixRecord struct {
+	Suite     string
+	Server    string
+	PublicKey string
+	Input     []*jsonCiphertext
+	Output    []*jsonCiphertext
+	Proof     []byte
+}
+
+// MarshalJSON encodes the record as a JSON object
+func (r *MixRecord) MarshalJSON() ([]byte, error) {
+	publicKey, err := r.PublicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	input, err := encodeJSONCiphertexts(r.Input)
+	if err != nil {
+		return nil, err
+	}
+	output, err := encodeJSONCiphertexts(r.Output)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonMixRecord{
+		Suite:     r.Suite,
+		Server:    r.Server,
+		PublicKey: hex.EncodeToString(publicKey),
+		Input:     input,
+		Output:    output,
+		Proof:     r.Proof,
+	})
+}
+
+// UnmarshalJSON decodes a record encoded by MarshalJSON
+// the points are decoded with the suite the record names, which must be a supported suite
+func (r *MixRecord) UnmarshalJSON(data []byte) error {
+	var encoded jsonMixRecord
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	suite, err := FindSuite(encoded.Suite)
+	if err != nil {
+		return err
+	}
+	publicKey, err := hex.DecodeString(encoded.PublicKey)
+	if err != nil {
+		return err
+	}
+	if r.PublicKey, err = decodePoint(suite, publicKey); err != nil {
+		return err
+	}
+	if r.Input, err = decodeJSONCiphertexts(suite, encoded.Input); err != nil {
+		return fmt.Errorf("input: %v", err)
+	}
+	if r.Output, err = decodeJSONCiphertexts(suite, encoded.Output); err != nil {
+		return fmt.Errorf("output: %v", err)
+	}
+	r.Suite = encoded.Suite
+	r.Server = encoded.Server
+	r.Proof = encoded.Proof
+	return nil
+}
+
+// SaveMixRecord writes a record to a file, in its JSON form
+func SaveMixRecord(path string, record *MixRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadMixRecord reads a record written by SaveMixRecord
+// a record of fewer than 2 ciphertexts is refused, as no shuffle could have produced it
+func LoadMixRecord(path string) (*MixRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	record := &MixRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("%s is not a mix record: %v", path, err)
+	}
+	if len(record.Input) < minShuffleLength || len(record.Output) < minShuffleLength {
+		return nil, fmt.Errorf("%s is not a mix record: %w: %d ciphertexts shuffled into %d", path, ErrInvalidShuffleProof, len(record.Input), len(record.Output))
+	}
+	return record, nil
+}
+
+// Mixer is a server of the mixnet
+// each server shuffles the list it is given, and publishes a record of it
+type Mixer interface {
+	Name() string                                                                        // the name of the server, as it appears in its records
+	Mix(suite suites.Suite, pubKey kyber.Point, input []*Ciphertext) (*MixRecord, error) // shuffles the input, and proves it
+}
+
+// LocalMixer is a mix server running in this process
+type LocalMixer struct {
+	ServerName string // the name of the server
+}
+
+// Name returns the name of the server
+func (m *LocalMixer) Name() string { return m.ServerName }
+
+// Mix re-encrypts and permutes the input with shuffle.Shuffle, and proves it
+// the permutation and the re-encryption randomness are forgotten as soon as the proof is made
+func (m *LocalMixer) Mix(suite suites.Suite, pubKey kyber.Point, input []*Ciphertext) (*MixRecord, error) {
+	record, err := ShuffleCiphertexts(suite, pubKey, input)
+	if err != nil {
+		return nil, err
+	}
+	record.Server = m.ServerName
+	return record, nil
+}
+
+// Mixnet coordinates a cascade of mix servers
+// the ciphertexts go through every server in turn, each taking the output of the previous one,
+// so they stay unlinkable as long as a single server is honest
+type Mixnet struct {
+	Suite     suites.Suite // the cipher suite of the election
+	PublicKey kyber.Point  // the public key the ciphertexts are encrypted with
+	Mixers    []Mixer      // the servers, in the order they mix
+}
+
+// NewMixnet creates a cascade of the given mix servers, for ciphertexts of the suite encrypted with pubKey
+func NewMixnet(suite suites.Suite, pubKey kyber.Point, mixers ...Mixer) *Mixnet {
+	return &Mixnet{Suite: suite, PublicKey: pubKey, Mixers: mixers}
+}
+
+// Run passes the ciphertexts through every server of the cascade
+// each record is checked as soon as its server publishes it, so a cheating server is caught before the next one runs
+// returns the output of the last server, and the records of every server, in order
+func (m *Mixnet) Run(input []*Ciphertext) (output []*Ciphertext, records []*MixRecord, err error) {
+	output = input
+	records = make([]*MixRecord, 0, len(m.Mixers))
+	for i, mixer := range m.Mixers {
+		record, err := mixer.Mix(m.Suite, m.PublicKey, output)
+		if err != nil {
+			return nil, records, fmt.Errorf("mix server %d (%s): %w", i, mixer.Name(), err)
+		}
+		if err := checkLink(m.PublicKey, output, record); err != nil {
+			return nil, records, fmt.Errorf("mix server %d (%s): %w", i, mixer.Name(), err)
+		}
+		if err := record.Verify(m.Suite); err != nil {
+			return nil, records, fmt.Errorf("mix server %d (%s): %w", i, mixer.Name(), err)
+		}
+		records = append(records, record)
+		output = record.Output
+	}
+	return // output, records, nil
+}
+
+// VerifyMixChain checks a whole cascade end-to-end, from the published records alone:
+// every server shuffled under the election's public key,
+// the first server was given the ciphertexts that were cast, each server was given the output of the previous one,
+// and every shuffle proof holds
+// returns the output of the last server, which is what gets decrypted
+func VerifyMixChain(suite suites.Suite, pubKey kyber.Point, input []*Ciphertext, records []*MixRecord) (output []*Ciphertext, err error) {
+	output = input
+	for i, record := range records {
+		if err := checkLink(pubKey, output, record); err != nil {
+			return nil, fmt.Errorf("mix %d (%s): %w", i, record.Server, err)
+		}
+		if err := record.Verify(suite); err != nil {
+			return nil, fmt.Errorf("mix %d (%s): %w", i, record.Server, err)
+		}
+		output = record.Output
+	}
+	return // output, nil
+}
+
+// helper function, checks that a record starts from the expected ciphertexts, encrypted with the expected key
+func checkLink(pubKey kyber.Point, expected []*Ciphertext, record *MixRecord) error {
+	if record == nil {
+		return fmt.Errorf("%w: the record is missing", ErrBrokenMixChain)
+	}
+	if record.PublicKey == nil || !record.PublicKey.Equal(pubKey) {
+		return fmt.Errorf("%w: ciphertexts were shuffled under another public key", ErrBrokenMixChain)
+	}
+	if len(record.Input) != len(expected) {
+		return fmt.Errorf("%w: input holds %d ciphertexts, expected %d", ErrBrokenMixChain, len(record.Input), len(expected))
+	}
+	for i := range expected {
+		if !record.Input[i].Equal(expected[i]) {
+			return fmt.Errorf("%w: input ciphertext %d doesn't match", ErrBrokenMixChain, i)
+		}
+	}
+	return nil
+}
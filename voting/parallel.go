@@ -0,0 +1,159 @@
+package voting
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"go.dedis.ch/kyber/v3"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// DecryptMessagesParallel decrypts a list of messages as DecryptMessages does, spreading the work over a pool of workers
+// the shadow of every trustee for every message is extracted concurrently, then the messages are decrypted concurrently
+// workers bounds the number of goroutines, 0 or less means runtime.GOMAXPROCS(0)
+// the output is the same as DecryptMessages: the decrypted messages in the order of the input,
+// and the sorted indices of the trustees that misbehaved
+// cancelling ctx stops handing out work, and returns ctx.Err()
+func DecryptMessagesParallel(ctx context.Context, suite suites.Suite, elGamal1, elGamal2 []kyber.Point, shares []*vss.DistKeyShare, threshold, contributorCount, workers int) (decryptedMessages []kyber.Point, misbehaving []int, err error) {
+	online, err := onlineShares(shares, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(elGamal2) != len(elGamal1) {
+		return nil, nil, fmt.Errorf("%d and %d el gamal halves given", len(elGamal1), len(elGamal2))
+	}
+	commits := online[0].Commitments()
+
+	// one task per message and trustee, in the order DecryptMessages goes through them
+	shadows := make([][]*VerifiableShadow, len(elGamal1))
+	for i := range shadows {
+		shadows[i] = make([]*VerifiableShadow, len(online))
+	}
+	failed, err := runPool(ctx, workers, len(elGamal1)*len(online), func(k int) (err error) {
+		i, j := k/len(online), k%len(online)
+		shadows[i][j], err = ExtractShadow(suite, elGamal1[i], elGamal2[i], online[j])
+		return // err
+	})
+	if err != nil {
+		if failed < 0 {
+			return nil, nil, err // cancelled
+		}
+		return nil, nil, fmt.Errorf("message %d: %w", failed/len(online), err)
+	}
+
+	return DecryptMessagesFromShadowsParallel(ctx, suite, elGamal1, elGamal2, shadows, commits, threshold, contributorCount, workers)
+}
+
+// DecryptMessagesFromShadowsParallel decrypts a list of messages from their shadows as DecryptMessagesFromShadows does,
+// with the messages spread over a pool of workers
+// if some messages can't be decrypted, the error is the one of the first of them,
+// and the misbehaving trustees are those caught up to it, exactly as DecryptMessagesFromShadows reports them
+func DecryptMessagesFromShadowsParallel(ctx context.Context, suite suites.Suite, elGamal1, elGamal2 []kyber.Point, shadows [][]*VerifiableShadow, commits []kyber.Point, threshold, contributorCount, workers int) (decryptedMessages []kyber.Point, misbehaving []int, err error) {
+	if len(elGamal2) != len(elGamal1) || len(shadows) != len(elGamal1) {
+		return nil, nil, fmt.Errorf("%d and %d el gamal halves given, with shadows for %d messages", len(elGamal1), len(elGamal2), len(shadows))
+	}
+
+	// every worker writes to its own slots, so the output keeps the order of the input
+	decryptedMessages = make([]kyber.Point, len(elGamal1))
+	faultyIndices := make([][]int, len(elGamal1))
+	failed, err := runPool(ctx, workers, len(elGamal1), func(i int) (err error) {
+		decryptedMessages[i], faultyIndices[i], err = DecryptMessageSecretless(suite, elGamal1[i], elGamal2[i], shadows[i], commits, threshold, contributorCount)
+		return // err
+	})
+	if err != nil && failed < 0 {
+		return nil, nil, err // cancelled
+	}
+
+	// only the messages up to the first failure are accounted for, as when going through them in order
+	last := len(elGamal1) - 1
+	if err != nil {
+		last = failed
+	}
+	faulty := make(map[int]bool)
+	for i := 0; i <= last; i++ {
+		for _, index := range faultyIndices[i] {
+			faulty[index] = true
+		}
+	}
+	if err != nil {
+		return nil, sortedIndices(faulty), fmt.Errorf("message %d: %w", failed, err)
+	}
+	return decryptedMessages, sortedIndices(faulty), nil
+}
+
+// helper function, keeps the shares of the trustees that are online, and checks there are enough of them
+func onlineShares(shares []*vss.DistKeyShare, threshold int) ([]*vss.DistKeyShare, error) {
+	online := make([]*vss.DistKeyShare, 0, len(shares))
+	for _, distShare := range shares {
+		if distShare != nil {
+			online = append(online, distShare)
+		}
+	}
+	if len(online) < threshold {
+		return nil, fmt.Errorf("%w: %d trustees online, threshold is %d", ErrInsufficientShadows, len(online), threshold)
+	}
+	return online, nil
+}
+
+// helper function, runs task(0) to task(n-1) on a pool of at most workers goroutines (runtime.GOMAXPROCS(0) if workers <= 0)
+// tasks are handed out in increasing order, and once a task fails, no task after it is started,
+// so every task before the first failure always runs, whatever the scheduling
+// returns the index and error of the first task that failed,
+// or -1 and the error of the context if it was cancelled before every task was handed out
+func runPool(ctx context.Context, workers, n int, task func(i int) error) (failed int, err error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var mu sync.Mutex
+	next := 0  // the next task to hand out
+	failed = n // the first task that failed so far, n if none did
+	var taskErr error
+	cancelled := false
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				if next >= n || next > failed {
+					mu.Unlock()
+					return
+				}
+				if ctx.Err() != nil {
+					cancelled = true
+					mu.Unlock()
+					return
+				}
+				i := next
+				next++
+				mu.Unlock()
+
+				if err := task(i); err != nil {
+					mu.Lock()
+					if i < failed {
+						failed, taskErr = i, err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cancelled {
+		return -1, ctx.Err()
+	}
+	if failed < n {
+		return failed, taskErr
+	}
+	return n, nil
+}
@@ -0,0 +1,165 @@
+package voting
+
+import (
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/proof/dleq"
+	"go.dedis.ch/kyber/v3/share"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// PartialDecryption is what a trustee publishes when decrypting a list of ciphertexts:
+// its shadow of every ciphertext, each with the proof that it was computed with the trustee's share
+// anyone holding the ciphertexts and the dkg commitments can check it, and combine threshold of them to decrypt
+type PartialDecryption struct {
+	Suite   string              // the name of the cipher suite the shadows belong to
+	Trustee int                 // the index of the trustee
+	Shadows []*VerifiableShadow // the shadows, one per ciphertext, in order
+}
+
+// ExtractPartialDecryption has a trustee extract its shadow of every ciphertext of a list
+func ExtractPartialDecryption(suite suites.Suite, ciphertexts []*Ciphertext, distShare *vss.DistKeyShare) (*PartialDecryption, error) {
+	partial := &PartialDecryption{
+		Suite:   suite.String(),
+		Trustee: distShare.PriShare().I,
+		Shadows: make([]*VerifiableShadow, len(ciphertexts)),
+	}
+	for i, ciphertext := range ciphertexts {
+		shadow, err := ExtractShadow(suite, ciphertext.C1, ciphertext.C2, distShare)
+		if err != nil {
+			return nil, fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+		partial.Shadows[i] = shadow
+	}
+	return partial, nil
+}
+
+// Verify checks that the partial decryption holds a valid shadow of every ciphertext, all from its trustee
+func (d *PartialDecryption) Verify(suite suites.Suite, ciphertexts []*Ciphertext, commits []kyber.Point) error {
+	if err := checkSuite(suite, d.Suite, "partial decryption"); err != nil {
+		return err
+	}
+	if len(d.Shadows) != len(ciphertexts) {
+		return fmt.Errorf("%w: %d shadows for %d ciphertexts", ErrInvalidShadow, len(d.Shadows), len(ciphertexts))
+	}
+	for i, shadow := range d.Shadows {
+		if shadow == nil || shadow.Shadow == nil || shadow.Shadow.I != d.Trustee {
+			return fmt.Errorf("%w: ciphertext %d: shadow doesn't belong to trustee %d", ErrInvalidShadow, i, d.Trustee)
+		}
+		if err := VerifyShadow(suite, ciphertexts[i].C1, shadow, commits); err != nil {
+			return fmt.Errorf("ciphertext %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// DecryptFromPartials decrypts a list of ciphertexts from the partial decryptions the trustees published for it
+// partial decryptions of another length are ignored, and shadows that fail verification are left out, as for DecryptMessagesFromShadows
+// returns the decrypted messages, and the sorted indices of the trustees that misbehaved
+func DecryptFromPartials(suite suites.Suite, ciphertexts []*Ciphertext, partials []*PartialDecryption, commits []kyber.Point, threshold, contributorCount int) (decryptedMessages []kyber.Point, misbehaving []int, err error) {
+	shadows := make([][]*VerifiableShadow, len(ciphertexts))
+	for i := range ciphertexts {
+		shadows[i] = make([]*VerifiableShadow, 0, len(partials))
+		for _, partial := range partials {
+			if partial != nil && partial.Suite == suite.String() && len(partial.Shadows) == len(ciphertexts) {
+				shadows[i] = append(shadows[i], partial.Shadows[i])
+			}
+		}
+	}
+	elGamal1, elGamal2, err := SplitCiphertexts(ciphertexts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return DecryptMessagesFromShadows(suite, elGamal1, elGamal2, shadows, commits, threshold, contributorCount)
+}
+
+// the JSON form of a partial decryption
+type jsonPartialDecryption struct {
+	Suite   string
+	Trustee int
+	Shadows []*jsonShadow
+}
+
+// the JSON form of a shadow, with every point and scalar hex encoded
+type jsonShadow struct {
+	Index int    // the index of the trustee
+	Value string // the partial decryption g^(y*x_i)
+	C     string // the challenge of the proof
+	R     string // the response of the proof
+	VG    string // the commitment of the proof for the base point
+	VH    string // the commitment of the proof for the first half of the ciphertext
+}
+
+// MarshalJSON encodes the partial decryption as a JSON object
+func (d *PartialDecryption) MarshalJSON() ([]byte, error) {
+	encoded := jsonPartialDecryption{Suite: d.Suite, Trustee: d.Trustee, Shadows: make([]*jsonShadow, len(d.Shadows))}
+	for i, shadow := range d.Shadows {
+		if shadow == nil || shadow.Shadow == nil || shadow.Proof == nil {
+			return nil, fmt.Errorf("%w: shadow %d is incomplete", ErrInvalidShadow, i)
+		}
+		values := make([]string, 5)
+		for j, value := range []encoding.BinaryMarshaler{shadow.Shadow.V, shadow.Proof.C, shadow.Proof.R, shadow.Proof.VG, shadow.Proof.VH} {
+			data, err := value.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			values[j] = hex.EncodeToString(data)
+		}
+		encoded.Shadows[i] = &jsonShadow{Index: shadow.Shadow.I, Value: values[0], C: values[1], R: values[2], VG: values[3], VH: values[4]}
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a partial decryption encoded by MarshalJSON
+// the points and scalars are decoded with the suite the partial decryption names, which must be a supported suite
+func (d *PartialDecryption) UnmarshalJSON(data []byte) error {
+	var encoded jsonPartialDecryption
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	suite, err := FindSuite(encoded.Suite)
+	if err != nil {
+		return err
+	}
+	shadows := make([]*VerifiableShadow, len(encoded.Shadows))
+	for i, wire := range encoded.Shadows {
+		if shadows[i], err = wire.decode(suite); err != nil {
+			return fmt.Errorf("shadow %d: %v", i, err)
+		}
+	}
+	d.Suite = encoded.Suite
+	d.Trustee = encoded.Trustee
+	d.Shadows = shadows
+	return nil
+}
+
+// decodes the JSON form of a shadow, with points and scalars of the given suite
+func (encoded *jsonShadow) decode(suite suites.Suite) (*VerifiableShadow, error) {
+	if encoded == nil {
+		return nil, fmt.Errorf("%w: shadow is missing", ErrInvalidShadow)
+	}
+	raw := make([][]byte, 5)
+	for j, value := range []string{encoded.Value, encoded.C, encoded.R, encoded.VG, encoded.VH} {
+		var err error
+		if raw[j], err = hex.DecodeString(value); err != nil {
+			return nil, err
+		}
+	}
+	points, err := decodePoints(suite, [][]byte{raw[0], raw[3], raw[4]})
+	if err != nil {
+		return nil, err
+	}
+	scalars, err := decodeScalars(suite, [][]byte{raw[1], raw[2]})
+	if err != nil {
+		return nil, err
+	}
+	return &VerifiableShadow{
+		Shadow: &share.PubShare{I: encoded.Index, V: points[0]},
+		Proof:  &dleq.Proof{C: scalars[0], R: scalars[1], VG: points[1], VH: points[2]},
+	}, nil
+}
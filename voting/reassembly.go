@@ -0,0 +1,223 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.dedis.ch/kyber/v3"
+)
+
+// ErrConflictingChunks is returned when two different chunks claim the same position of a long message
+// only the voter knows the random prefix of their message, so a conflict means they submitted it twice, differently
+var ErrConflictingChunks = errors.New("conflicting chunks for the same position")
+
+// ErrDuplicateChunks is returned when the same chunk of a long message was found more than once
+var ErrDuplicateChunks = errors.New("duplicate chunks")
+
+// ReassemblyStatus tells what came of a long ballot when its chunks were put back together
+type ReassemblyStatus int
+
+// the states of a reassembled ballot
+const (
+	ReassemblyComplete    ReassemblyStatus = iota // every chunk was found once, and the message decoded
+	ReassemblyDuplicated                          // the message decoded, but some chunks were found more than once
+	ReassemblyIncomplete                          // chunks are missing, or the message is truncated or was tampered with
+	ReassemblyConflicting                         // two different chunks claim the same position
+)
+
+// the names of the states
+var reassemblyStatusNames = []string{"complete", "duplicated", "incomplete", "conflicting"}
+
+// String returns the name of the state
+func (s ReassemblyStatus) String() string {
+	if s < 0 || int(s) >= len(reassemblyStatusNames) {
+		return fmt.Sprintf("status %d", int(s))
+	}
+	return reassemblyStatusNames[s]
+}
+
+// ReassemblyAction tells what to do with a ballot that isn't complete
+type ReassemblyAction int
+
+// the actions of a reassembly policy
+const (
+	DropBallot     ReassemblyAction = iota // the ballot is left out of the messages, and listed in the report
+	KeepBallot                             // the ballot is kept, only possible when its message decoded
+	FailReassembly                         // the whole reassembly fails
+)
+
+// ReassemblyPolicy tells what to do with each kind of ballot that isn't complete
+// complete ballots are always kept
+type ReassemblyPolicy struct {
+	Duplicated  ReassemblyAction // ballots whose duplicate chunks were merged
+	Incomplete  ReassemblyAction // ballots missing chunks or failing their integrity tag, and portions that hold no chunk at all
+	Conflicting ReassemblyAction // ballots with two different chunks in the same position
+}
+
+// DefaultReassemblyPolicy keeps the ballots whose duplicate chunks could be merged, and drops the ones that can't be read
+var DefaultReassemblyPolicy = ReassemblyPolicy{Duplicated: KeepBallot, Incomplete: DropBallot, Conflicting: DropBallot}
+
+// StrictReassemblyPolicy merges duplicate chunks, and fails on any ballot that can't be read
+var StrictReassemblyPolicy = ReassemblyPolicy{Duplicated: KeepBallot, Incomplete: FailReassembly, Conflicting: FailReassembly}
+
+// helper function, the action the policy takes for a ballot
+func (p ReassemblyPolicy) action(status ReassemblyStatus) ReassemblyAction {
+	switch status {
+	case ReassemblyDuplicated:
+		return p.Duplicated
+	case ReassemblyIncomplete:
+		return p.Incomplete
+	case ReassemblyConflicting:
+		return p.Conflicting
+	}
+	return KeepBallot
+}
+
+// helper function, checks that the policy only keeps ballots it has a message for
+func (p ReassemblyPolicy) validate() error {
+	if p.Incomplete == KeepBallot || p.Conflicting == KeepBallot {
+		return errors.New("incomplete and conflicting ballots have no message to keep")
+	}
+	for _, action := range []ReassemblyAction{p.Duplicated, p.Incomplete, p.Conflicting} {
+		if action < DropBallot || action > FailReassembly {
+			return fmt.Errorf("unknown reassembly action %d", int(action))
+		}
+	}
+	return nil
+}
+
+// ReassembledBallot is what came of the chunks sharing one random prefix
+type ReassembledBallot struct {
+	Prefix  []byte           // the random prefix shared by the chunks
+	Status  ReassemblyStatus // whether the ballot is complete, or what went wrong
+	Chunks  int              // the number of chunks found, duplicates included
+	Message string           // the message, if it decoded
+	Kept    bool             // whether the policy kept the ballot
+	Err     error            // why the ballot isn't complete, nil if it is
+}
+
+// ReassemblyReport is the outcome of putting long ballots back together
+type ReassemblyReport struct {
+	Ballots    []*ReassembledBallot // every ballot found, in the order of their prefixes
+	Messages   []string             // the messages of the ballots kept, in the same order
+	Unreadable map[int]error        // the portions that don't hold a chunk, by index
+}
+
+// Err sums up what went wrong: one error per portion that holds no chunk, and per ballot that isn't complete
+// returns nil if every ballot is complete
+func (r *ReassemblyReport) Err() error {
+	errs := make([]error, 0)
+	indices := make([]int, 0, len(r.Unreadable))
+	for i := range r.Unreadable {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	for _, i := range indices {
+		errs = append(errs, fmt.Errorf("message portion %d: %w", i, r.Unreadable[i]))
+	}
+	for i, ballot := range r.Ballots {
+		if ballot.Err != nil {
+			errs = append(errs, fmt.Errorf("ballot %d (%s): %w", i, ballot.Status, ballot.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReassembleMessages puts long ballots back together from all their decrypted message portions, in any order
+// the chunks are grouped by random prefix, and every group is checked and decoded on its own,
+// so the report has one entry per ballot saying whether it is complete, duplicated, incomplete or conflicting
+// the ballots come in the order of their prefixes, whatever the order of the portions
+// the policy decides which ballots are kept, dropped, or fail the whole reassembly;
+// on failure, the report so far is returned along with the error of the ballot
+func ReassembleMessages(decryptedMessages []kyber.Point, policy ReassemblyPolicy) (report *ReassemblyReport, err error) {
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	report = &ReassemblyReport{Ballots: make([]*ReassembledBallot, 0), Messages: make([]string, 0), Unreadable: make(map[int]error)}
+
+	// find the decoding of all decrypted messages
+	messageChunks := make([][]byte, 0, len(decryptedMessages))
+	for i := range decryptedMessages {
+		data, err := decryptedMessages[i].Data() // retrieve the data from the point
+		if err != nil {
+			report.Unreadable[i] = fmt.Errorf("%w: %v", ErrEmbedding, err)
+		} else if len(data) <= randomnessLength { // too short for the prefix and the positional byte
+			report.Unreadable[i] = fmt.Errorf("%w: holds only %d bytes", ErrEmbedding, len(data))
+		} else {
+			messageChunks = append(messageChunks, data)
+		}
+		if report.Unreadable[i] != nil && policy.Incomplete == FailReassembly {
+			return report, fmt.Errorf("message portion %d: %w", i, report.Unreadable[i])
+		}
+	}
+
+	// all similarly prefixed chunks are organized together, in increasing order of positional index,
+	// and copies of a chunk end up next to each other
+	sort.Sort(SortableBytesList(messageChunks))
+
+	for start := 0; start < len(messageChunks); {
+		prefix := messageChunks[start][0:randomnessLength]
+		end := start + 1
+		for end < len(messageChunks) && isDataEqual(prefix, messageChunks[end][0:randomnessLength]) {
+			end++
+		}
+		ballot := reassembleBallot(messageChunks[start:end])
+		report.Ballots = append(report.Ballots, ballot)
+		start = end
+
+		switch policy.action(ballot.Status) {
+		case KeepBallot:
+			ballot.Kept = true
+			report.Messages = append(report.Messages, ballot.Message)
+		case FailReassembly:
+			return report, fmt.Errorf("ballot %d (%s): %w", len(report.Ballots)-1, ballot.Status, ballot.Err)
+		}
+	}
+
+	return // report, nil
+}
+
+// helper function, checks and decodes the sorted chunks of a single ballot
+func reassembleBallot(chunks [][]byte) *ReassembledBallot {
+	ballot := &ReassembledBallot{Prefix: chunks[0][0:randomnessLength], Status: ReassemblyComplete, Chunks: len(chunks)}
+
+	// merge the copies of a chunk, which are next to each other
+	unique := make([][]byte, 0, len(chunks))
+	for _, chunk := range chunks {
+		if len(unique) > 0 {
+			last := unique[len(unique)-1]
+			if last[randomnessLength] == chunk[randomnessLength] { // the same position as the previous chunk
+				if !isDataEqual(last, chunk) {
+					ballot.Status = ReassemblyConflicting
+					ballot.Err = fmt.Errorf("%w: position %d", ErrConflictingChunks, chunk[randomnessLength])
+					return ballot
+				}
+				ballot.Status = ReassemblyDuplicated
+				continue
+			}
+		}
+		unique = append(unique, chunk)
+	}
+
+	// every position must be there, once
+	for i, chunk := range unique {
+		if int(chunk[randomnessLength]) != i {
+			ballot.Status = ReassemblyIncomplete
+			ballot.Err = fmt.Errorf("%w: chunk %d is missing", ErrCorruptMessage, i)
+			return ballot
+		}
+	}
+
+	data, err := decodeLongMessage(unique)
+	if err != nil {
+		ballot.Status = ReassemblyIncomplete
+		ballot.Err = err
+		return ballot
+	}
+	ballot.Message = string(data)
+	if ballot.Status == ReassemblyDuplicated {
+		ballot.Err = fmt.Errorf("%w: %d chunks found for %d positions", ErrDuplicateChunks, len(chunks), len(unique))
+	}
+	return ballot
+}
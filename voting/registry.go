@@ -0,0 +1,263 @@
+package voting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the name of the ballot signature, signed along with the ballot
+const ballotSignatureDomain = "crypto-voting/ballot-signature"
+
+// ErrUnknownVoter is returned for a ballot cast by someone who isn't in the voter registry
+var ErrUnknownVoter = errors.New("voter is not registered")
+
+// ErrInvalidBallotSignature is returned for a ballot that isn't signed by the key its voter registered
+var ErrInvalidBallotSignature = errors.New("invalid ballot signature")
+
+// ErrAlreadyVoted is returned for a second ballot from the same voter, when only their first ballot counts
+var ErrAlreadyVoted = errors.New("voter already cast a ballot")
+
+// ErrSupersededBallot is returned for a ballot replaced by a later ballot from the same voter, when only their last ballot counts
+var ErrSupersededBallot = errors.New("ballot was replaced by a later ballot from the same voter")
+
+// RevotePolicy tells what happens when a voter casts more than one ballot
+type RevotePolicy int
+
+// the re-voting policies
+const (
+	FirstVoteCounts RevotePolicy = iota // a voter casts a single ballot, and any later one is rejected
+	LastVoteCounts                      // a voter may vote again, and only their last ballot is counted
+)
+
+// the names of the policies, as they are written to JSON
+var revotePolicyNames = []string{"first-vote-counts", "last-vote-counts"}
+
+// String returns the name of the policy
+func (p RevotePolicy) String() string {
+	if p < 0 || int(p) >= len(revotePolicyNames) {
+		return fmt.Sprintf("policy %d", int(p))
+	}
+	return revotePolicyNames[p]
+}
+
+// MarshalText encodes the policy as its name
+func (p RevotePolicy) MarshalText() ([]byte, error) {
+	if p < 0 || int(p) >= len(revotePolicyNames) {
+		return nil, fmt.Errorf("unknown re-voting policy %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a policy from its name
+func (p *RevotePolicy) UnmarshalText(text []byte) error {
+	for i, name := range revotePolicyNames {
+		if string(text) == name {
+			*p = RevotePolicy(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown re-voting policy %q", text)
+}
+
+// Registry lists the voters eligible in an election, each with the public key they sign their ballots with
+// the keys are Schnorr keys of the election's suite, every voter holds their own private key
+// the registry is public: anyone can check that every counted ballot comes from a registered voter, and from only one
+type Registry struct {
+	Suite      string                 // the name of the cipher suite of the keys
+	ElectionID string                 // the election the voters are registered in
+	Policy     RevotePolicy           // what happens when a voter casts more than one ballot
+	Voters     map[string]kyber.Point // the public key of each voter, by voter ID
+}
+
+// NewRegistry creates an empty registry for an election
+func NewRegistry(suite suites.Suite, electionID string, policy RevotePolicy) *Registry {
+	return &Registry{Suite: suite.String(), ElectionID: electionID, Policy: policy, Voters: make(map[string]kyber.Point)}
+}
+
+// NewVoterKey generates a voter's signing key pair
+// the private key stays with the voter, the public key goes to the registry
+func NewVoterKey(suite suites.Suite) (private kyber.Scalar, public kyber.Point) {
+	private = suite.Scalar().Pick(suite.RandomStream())
+	public = suite.Point().Mul(private, nil)
+	return // private, public
+}
+
+// Register adds a voter to the registry, with the public key they sign their ballots with
+// a voter can only be registered once
+func (r *Registry) Register(voterID string, public kyber.Point) error {
+	if voterID == "" {
+		return errors.New("voter ID is empty")
+	}
+	if _, ok := r.Voters[voterID]; ok {
+		return fmt.Errorf("voter %q is already registered", voterID)
+	}
+	r.Voters[voterID] = public
+	return nil
+}
+
+// Len returns the number of registered voters
+func (r *Registry) Len() int { return len(r.Voters) }
+
+// VerifyBallot checks that a ballot comes from a registered voter, and is signed with their key
+// the proofs of the ballot are checked separately, see Ballot.Verify
+func (r *Registry) VerifyBallot(suite suites.Suite, ballot *Ballot) error {
+	if err := checkSuite(suite, r.Suite, "voter registry"); err != nil {
+		return err
+	}
+	if ballot.ElectionID != r.ElectionID {
+		return fmt.Errorf("ballot was cast in election %q, the registry is for %q", ballot.ElectionID, r.ElectionID)
+	}
+	public, ok := r.Voters[ballot.VoterID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownVoter, ballot.VoterID)
+	}
+	return ballot.VerifySignature(suite, public)
+}
+
+// Sign has the voter sign the ballot with their private key, over its canonical encoding
+// the ballot must be complete: any change to it afterwards breaks the signature
+func (b *Ballot) Sign(suite suites.Suite, private kyber.Scalar) error {
+	message, err := b.signedMessage()
+	if err != nil {
+		return err
+	}
+	signature, err := schnorr.Sign(suite, private, message)
+	if err != nil {
+		return err
+	}
+	b.Signature = signature
+	return nil
+}
+
+// VerifySignature checks the signature of the ballot against the voter's public key
+func (b *Ballot) VerifySignature(suite suites.Suite, public kyber.Point) error {
+	if len(b.Signature) == 0 {
+		return fmt.Errorf("%w: ballot isn't signed", ErrInvalidBallotSignature)
+	}
+	message, err := b.signedMessage()
+	if err != nil {
+		return err
+	}
+	if err := schnorr.Verify(suite, public, message, b.Signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBallotSignature, err)
+	}
+	return nil
+}
+
+// helper function, what the voter signs: the canonical encoding of the ballot without its signature
+func (b *Ballot) signedMessage() ([]byte, error) {
+	unsigned := *b
+	unsigned.Signature = nil
+	data, err := unsigned.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(ballotSignatureDomain), data...), nil
+}
+
+// helper function, applies a re-voting policy to the ballots of a list that passed every other check
+// under FirstVoteCounts, a later ballot of the same voter is rejected, under LastVoteCounts, an earlier one is
+// returns the indices of the ballots that count, in order, and the reason each other ballot was turned away
+func applyRevotePolicy(ballots []*Ballot, indices []int, policy RevotePolicy) (counted []int, rejected map[int]error) {
+	rejected = make(map[int]error)
+	kept := make(map[string]int) // the index of the ballot that counts for each voter so far
+	for _, i := range indices {
+		voterID := ballots[i].VoterID
+		previous, ok := kept[voterID]
+		switch {
+		case !ok:
+			kept[voterID] = i
+		case policy == LastVoteCounts:
+			rejected[previous] = fmt.Errorf("%w: voter %q", ErrSupersededBallot, voterID)
+			kept[voterID] = i
+		default:
+			rejected[i] = fmt.Errorf("%w: voter %q", ErrAlreadyVoted, voterID)
+		}
+	}
+	counted = make([]int, 0, len(kept))
+	for _, i := range kept {
+		counted = append(counted, i)
+	}
+	sort.Ints(counted)
+	return // counted, rejected
+}
+
+// the JSON form of a registry, with every key hex encoded
+type jsonRegistry struct {
+	Suite      string
+	ElectionID string
+	Policy     RevotePolicy
+	Voters     map[string]string
+}
+
+// MarshalJSON encodes the registry as a JSON object
+func (r *Registry) MarshalJSON() ([]byte, error) {
+	encoded := jsonRegistry{Suite: r.Suite, ElectionID: r.ElectionID, Policy: r.Policy, Voters: make(map[string]string, len(r.Voters))}
+	for voterID, public := range r.Voters {
+		data, err := public.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		encoded.Voters[voterID] = hex.EncodeToString(data)
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a registry encoded by MarshalJSON, with the suite it names
+func (r *Registry) UnmarshalJSON(data []byte) error {
+	var encoded jsonRegistry
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	suite, err := FindSuite(encoded.Suite)
+	if err != nil {
+		return err
+	}
+	voters := make(map[string]kyber.Point, len(encoded.Voters))
+	for voterID, key := range encoded.Voters {
+		raw, err := hex.DecodeString(key)
+		if err != nil {
+			return fmt.Errorf("voter %q: %v", voterID, err)
+		}
+		public := suite.Point()
+		if err := public.UnmarshalBinary(raw); err != nil {
+			return fmt.Errorf("voter %q: %v", voterID, err)
+		}
+		voters[voterID] = public
+	}
+	r.Suite = encoded.Suite
+	r.ElectionID = encoded.ElectionID
+	r.Policy = encoded.Policy
+	r.Voters = voters
+	return nil
+}
+
+// SaveRegistry writes the registry to a file, as JSON
+func SaveRegistry(path string, registry *Registry) error {
+	data, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadRegistry reads a registry written by SaveRegistry
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	registry := &Registry{}
+	if err := json.Unmarshal(data, registry); err != nil {
+		return nil, fmt.Errorf("%s is not a voter registry: %v", path, err)
+	}
+	return registry, nil
+}
@@ -0,0 +1,390 @@
+package voting
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the version of the binary encoding of selections, written first
+const selectionEncodingVersion = 1
+
+// the name of the schema fingerprint, hashed into it
+const schemaFingerprintDomain = "crypto-voting/ballot-schema"
+
+// the length of the schema fingerprint written into every encoding, a truncated sha256
+const schemaFingerprintLength = 4
+
+// ErrInvalidSchema is returned for a ballot schema that ballots can't be filled in against
+var ErrInvalidSchema = errors.New("invalid ballot schema")
+
+// ErrInvalidSelection is returned for selections that break the rules of a ballot schema,
+// such as too many candidates selected, or a candidate that doesn't exist
+var ErrInvalidSelection = errors.New("invalid selection")
+
+// ErrMalformedBallot is returned for a plaintext that isn't an encoding of selections under the schema
+var ErrMalformedBallot = errors.New("malformed ballot plaintext")
+
+// BallotSchema describes what a long ballot holds: the questions on it, how many candidates may be selected in each,
+// and how many names may be written in
+// selections under the schema are encoded compactly (see EncodeSelections), and encrypted through the long message path
+type BallotSchema struct {
+	ElectionID    string            // the election the schema belongs to
+	Questions     []*BallotQuestion // the questions on the ballot, in order
+	WriteInLength int               // the longest name that can be written in, in bytes
+}
+
+// BallotQuestion is a question of a ballot schema
+type BallotQuestion struct {
+	ID            string   // a short identifier of the question, unique within the schema
+	Text          string   // the question, as shown to the voters
+	Candidates    []string // the names of the candidates, in order
+	MinSelections int      // the fewest answers a voter may give, 0 allows a blank answer
+	MaxSelections int      // the most answers a voter may give, candidates and write-ins together
+	WriteIns      int      // the most names a voter may write in, 0 if write-ins aren't allowed
+}
+
+// Selection is a voter's answer to a question of a ballot schema
+type Selection struct {
+	Candidates []int    // the indices of the selected candidates, in increasing order
+	WriteIns   []string // the names written in, in the order the voter gave them
+}
+
+// NewBallotSchema creates the schema of an election's ballots from its manifest:
+// every question is answered by picking exactly one of its candidates, with no write-ins
+func NewBallotSchema(manifest *Manifest) *BallotSchema {
+	schema := &BallotSchema{ElectionID: manifest.ElectionID, Questions: make([]*BallotQuestion, len(manifest.Questions))}
+	for i, question := range manifest.Questions {
+		schema.Questions[i] = &BallotQuestion{ID: question.ID, Text: question.Text, Candidates: question.Candidates, MinSelections: 1, MaxSelections: 1}
+	}
+	return schema
+}
+
+// Validate checks that the schema describes ballots that can be filled in, and that its encodings fit in a long message
+func (s *BallotSchema) Validate() error {
+	if s.ElectionID == "" {
+		return fmt.Errorf("%w: no election ID", ErrInvalidSchema)
+	}
+	if len(s.Questions) == 0 {
+		return fmt.Errorf("%w: no questions", ErrInvalidSchema)
+	}
+	questionIDs := make(map[string]bool, len(s.Questions))
+	writeIns := false
+	for i, question := range s.Questions {
+		if question == nil || question.ID == "" {
+			return fmt.Errorf("%w: question %d has no ID", ErrInvalidSchema, i)
+		}
+		if questionIDs[question.ID] {
+			return fmt.Errorf("%w: question ID %q is used twice", ErrInvalidSchema, question.ID)
+		}
+		questionIDs[question.ID] = true
+		if len(question.Candidates) > 0 || question.WriteIns == 0 {
+			if err := checkNames(question.Candidates); err != nil {
+				return fmt.Errorf("%w: candidates of question %q: %v", ErrInvalidSchema, question.ID, err)
+			}
+		}
+		if question.WriteIns < 0 {
+			return fmt.Errorf("%w: question %q allows %d write-ins", ErrInvalidSchema, question.ID, question.WriteIns)
+		}
+		if question.MaxSelections < 1 || question.MaxSelections > len(question.Candidates)+question.WriteIns {
+			return fmt.Errorf("%w: question %q allows %d selections out of %d candidates and %d write-ins",
+				ErrInvalidSchema, question.ID, question.MaxSelections, len(question.Candidates), question.WriteIns)
+		}
+		if question.MinSelections < 0 || question.MinSelections > question.MaxSelections {
+			return fmt.Errorf("%w: question %q asks for %d to %d selections", ErrInvalidSchema, question.ID, question.MinSelections, question.MaxSelections)
+		}
+		writeIns = writeIns || question.WriteIns > 0
+	}
+	if writeIns && s.WriteInLength < 1 {
+		return fmt.Errorf("%w: write-ins are allowed, but can't be longer than %d bytes", ErrInvalidSchema, s.WriteInLength)
+	}
+	if length := s.MaxEncodedLength(); length > 0xFFFF {
+		return fmt.Errorf("%w: selections take up to %d bytes, too long for a long message", ErrInvalidSchema, length)
+	}
+	return nil
+}
+
+// helper function, the number of bytes x takes as a uvarint
+func uvarintLength(x int) int {
+	return len(binary.AppendUvarint(nil, uint64(x)))
+}
+
+// MaxEncodedLength returns the length of the longest encoding of selections under the schema, in bytes
+func (s *BallotSchema) MaxEncodedLength() int {
+	length := 1 + schemaFingerprintLength // version and fingerprint
+	for _, question := range s.Questions {
+		selections := min(question.MaxSelections, len(question.Candidates))
+		writeIns := min(question.MaxSelections, question.WriteIns)
+		length += uvarintLength(selections) + selections*uvarintLength(len(question.Candidates))
+		length += uvarintLength(writeIns) + writeIns*(uvarintLength(s.WriteInLength)+s.WriteInLength)
+	}
+	return length
+}
+
+// Chunks returns the number of chunks every long ballot of the schema is padded to, so that all of them look alike
+func (s *BallotSchema) Chunks(suite suites.Suite) int {
+	return LongMessageChunks(suite, s.MaxEncodedLength())
+}
+
+// helper function, the first bytes of a hash of the schema, written into every encoding
+// so that selections made under another schema (or another election) aren't decoded by mistake
+func (s *BallotSchema) fingerprint() []byte {
+	data, _ := json.Marshal(s) // the schema only holds strings and numbers, which always encode
+	hash := sha256.Sum256(append([]byte(schemaFingerprintDomain), data...))
+	return hash[:schemaFingerprintLength]
+}
+
+// CheckSelections checks that the selections answer every question of the schema, within its rules
+// a nil selection is a blank answer
+func (s *BallotSchema) CheckSelections(selections []*Selection) error {
+	if len(selections) != len(s.Questions) {
+		return fmt.Errorf("%w: %d answers for %d questions", ErrInvalidSelection, len(selections), len(s.Questions))
+	}
+	for i, question := range s.Questions {
+		selection := selections[i]
+		if selection == nil {
+			selection = &Selection{}
+		}
+		for k, candidate := range selection.Candidates {
+			if candidate < 0 || candidate >= len(question.Candidates) {
+				return fmt.Errorf("%w: question %q has no candidate %d", ErrInvalidSelection, question.ID, candidate)
+			}
+			if k > 0 && candidate <= selection.Candidates[k-1] {
+				return fmt.Errorf("%w: candidates of question %q aren't listed once each, in increasing order", ErrInvalidSelection, question.ID)
+			}
+		}
+		if len(selection.WriteIns) > question.WriteIns {
+			return fmt.Errorf("%w: %d names written in for question %q, %d allowed", ErrInvalidSelection, len(selection.WriteIns), question.ID, question.WriteIns)
+		}
+		for _, name := range selection.WriteIns {
+			if name == "" || len(name) > s.WriteInLength || !utf8.ValidString(name) {
+				return fmt.Errorf("%w: write-in %q for question %q isn't a name of 1 to %d bytes", ErrInvalidSelection, name, question.ID, s.WriteInLength)
+			}
+		}
+		count := len(selection.Candidates) + len(selection.WriteIns)
+		if count < question.MinSelections || count > question.MaxSelections {
+			return fmt.Errorf("%w: %d answers for question %q, which takes %d to %d", ErrInvalidSelection, count, question.ID, question.MinSelections, question.MaxSelections)
+		}
+	}
+	return nil
+}
+
+// EncodeSelections encodes the answers to the questions of the schema, checked with CheckSelections
+// the encoding is [version][schema fingerprint], then for every question, with every number a uvarint:
+// [number of candidates][candidate indices][number of write-ins][length of the name][name]...
+// every valid set of selections has exactly one encoding
+func (s *BallotSchema) EncodeSelections(selections []*Selection) ([]byte, error) {
+	if err := s.CheckSelections(selections); err != nil {
+		return nil, err
+	}
+	data := append([]byte{selectionEncodingVersion}, s.fingerprint()...)
+	for _, selection := range selections {
+		if selection == nil {
+			selection = &Selection{}
+		}
+		data = binary.AppendUvarint(data, uint64(len(selection.Candidates)))
+		for _, candidate := range selection.Candidates {
+			data = binary.AppendUvarint(data, uint64(candidate))
+		}
+		data = binary.AppendUvarint(data, uint64(len(selection.WriteIns)))
+		for _, name := range selection.WriteIns {
+			data = binary.AppendUvarint(data, uint64(len(name)))
+			data = append(data, name...)
+		}
+	}
+	return data, nil
+}
+
+// DecodeSelections reads selections back from a plaintext made by EncodeSelections
+// returns an error wrapping ErrMalformedBallot if the plaintext isn't an encoding under this schema,
+// and ErrInvalidSelection if it decodes to selections that break the rules of the schema
+func (s *BallotSchema) DecodeSelections(data []byte) (selections []*Selection, err error) {
+	if len(data) < 1+schemaFingerprintLength {
+		return nil, fmt.Errorf("%w: %d bytes is too short for the header", ErrMalformedBallot, len(data))
+	}
+	if data[0] != selectionEncodingVersion {
+		return nil, fmt.Errorf("%w: unknown encoding version %d", ErrMalformedBallot, data[0])
+	}
+	if !isDataEqual(data[1:1+schemaFingerprintLength], s.fingerprint()) {
+		return nil, fmt.Errorf("%w: selections were made under another schema", ErrMalformedBallot)
+	}
+	rest := data[1+schemaFingerprintLength:]
+
+	// helper function, reads the next number, refusing any larger than limit
+	next := func(limit int) (int, error) {
+		x, n := binary.Uvarint(rest)
+		if n <= 0 {
+			return 0, fmt.Errorf("%w: truncated number", ErrMalformedBallot)
+		}
+		if x > uint64(limit) {
+			return 0, fmt.Errorf("%w: %d is larger than %d", ErrMalformedBallot, x, limit)
+		}
+		rest = rest[n:]
+		return int(x), nil
+	}
+
+	selections = make([]*Selection, len(s.Questions))
+	for i, question := range s.Questions {
+		selection := &Selection{Candidates: make([]int, 0), WriteIns: make([]string, 0)}
+		count, err := next(min(question.MaxSelections, len(question.Candidates)))
+		if err != nil {
+			return nil, fmt.Errorf("question %q: %w", question.ID, err)
+		}
+		for k := 0; k < count; k++ {
+			candidate, err := next(len(question.Candidates) - 1)
+			if err != nil {
+				return nil, fmt.Errorf("question %q: %w", question.ID, err)
+			}
+			selection.Candidates = append(selection.Candidates, candidate)
+		}
+		if count, err = next(question.WriteIns); err != nil {
+			return nil, fmt.Errorf("question %q: %w", question.ID, err)
+		}
+		for k := 0; k < count; k++ {
+			length, err := next(s.WriteInLength)
+			if err != nil {
+				return nil, fmt.Errorf("question %q: %w", question.ID, err)
+			}
+			if len(rest) < length {
+				return nil, fmt.Errorf("%w: question %q: write-in of %d bytes is truncated", ErrMalformedBallot, question.ID, length)
+			}
+			selection.WriteIns = append(selection.WriteIns, string(rest[:length]))
+			rest = rest[length:]
+		}
+		selections[i] = selection
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: %d bytes left over", ErrMalformedBallot, len(rest))
+	}
+
+	// the selections must follow the rules, and have been encoded the one way they can be
+	encoded, err := s.EncodeSelections(selections)
+	if err != nil {
+		return nil, err
+	}
+	if !isDataEqual(encoded, data) {
+		return nil, fmt.Errorf("%w: not the canonical encoding", ErrMalformedBallot)
+	}
+	return // selections, nil
+}
+
+// EncryptSelections encodes the selections and encrypts them as a long message vector under h
+// every ballot of the schema takes the same number of chunks, see Chunks
+func (s *BallotSchema) EncryptSelections(suite suites.Suite, selections []*Selection, h kyber.Point) (messagePortions []kyber.Point, vector CiphertextVector, err error) {
+	data, err := s.EncodeSelections(selections)
+	if err != nil {
+		return nil, nil, err
+	}
+	messagePortions, elGamal1, elGamal2, err := EncryptLongMessageChunks(suite, data, h, s.Chunks(suite))
+	if err != nil {
+		return nil, nil, err
+	}
+	if vector, err = JoinCiphertexts(elGamal1, elGamal2); err != nil {
+		return nil, nil, err
+	}
+	return // messagePortions, vector, nil
+}
+
+// DecodeVector reads selections back from the decrypted portions of a vector made by EncryptSelections
+// returns an error wrapping ErrEmbedding or ErrCorruptMessage if the portions don't compile (see CompileVector),
+// and ErrMalformedBallot or ErrInvalidSelection if the message isn't valid selections (see DecodeSelections)
+func (s *BallotSchema) DecodeVector(decryptedPortions []kyber.Point) ([]*Selection, error) {
+	message, err := CompileVector(decryptedPortions)
+	if err != nil {
+		return nil, err
+	}
+	return s.DecodeSelections([]byte(message))
+}
+
+// helper function, the i-th sample selections: rotating through the candidates of every question,
+// with a write-in every third ballot wherever they are allowed
+func (s *BallotSchema) sampleSelections(i int) []*Selection {
+	selections := make([]*Selection, len(s.Questions))
+	for q, question := range s.Questions {
+		selection := &Selection{}
+		if question.WriteIns > 0 && i%3 == 2 {
+			name := fmt.Sprintf("Write-in #%d", i)
+			selection.WriteIns = []string{name[:min(len(name), s.WriteInLength)]}
+		} else if len(question.Candidates) > 0 {
+			selection.Candidates = []int{(i + q) % len(question.Candidates)}
+		}
+		selections[q] = selection
+	}
+	return selections
+}
+
+// GenerateSelectionVectors generates n sample ballots of the schema, each encrypted as a vector
+// the typed counterpart of GenerateLongMessageVectors: selections[i] are the answers encrypted in vectors[i],
+// and messages[i] its message portions
+// the schema must allow a single answer to every question
+func GenerateSelectionVectors(suite suites.Suite, schema *BallotSchema, n int, h kyber.Point) (selections [][]*Selection, messages [][]kyber.Point, vectors []CiphertextVector, err error) {
+	selections = make([][]*Selection, n)
+	messages = make([][]kyber.Point, n)
+	vectors = make([]CiphertextVector, n)
+	for i := 0; i < n; i++ {
+		selections[i] = schema.sampleSelections(i)
+		if messages[i], vectors[i], err = schema.EncryptSelections(suite, selections[i], h); err != nil {
+			return nil, nil, nil, fmt.Errorf("ballot %d: %w", i, err)
+		}
+	}
+	return // selections, messages, vectors, nil
+}
+
+// Format writes selections out by name, one question after the other, e.g. "mayor: Smith; council: Jones, Brown (write-in)"
+func (s *BallotSchema) Format(selections []*Selection) string {
+	answers := make([]string, 0, len(s.Questions))
+	for i, question := range s.Questions {
+		if i >= len(selections) {
+			break
+		}
+		names := make([]string, 0)
+		if selections[i] != nil {
+			for _, candidate := range selections[i].Candidates {
+				if candidate >= 0 && candidate < len(question.Candidates) {
+					names = append(names, question.Candidates[candidate])
+				}
+			}
+			for _, name := range selections[i].WriteIns {
+				names = append(names, name+" (write-in)")
+			}
+		}
+		if len(names) == 0 {
+			names = append(names, "(blank)")
+		}
+		answers = append(answers, question.ID+": "+strings.Join(names, ", "))
+	}
+	return strings.Join(answers, "; ")
+}
+
+// SaveSchema writes a ballot schema to a file, in its JSON form
+func SaveSchema(path string, schema *BallotSchema) error {
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadSchema reads and validates a ballot schema written by SaveSchema
+func LoadSchema(path string) (*BallotSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema := &BallotSchema{}
+	if err := json.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("%s is not a ballot schema: %v", path, err)
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
@@ -0,0 +1,334 @@
+package voting
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/proof"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/shuffle"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the name of the sequence shuffle proof protocol, hashed into the proof and the challenge
+const sequenceShuffleProtocol = "SequencesShuffle"
+
+// CiphertextVector is a message encrypted as several El Gamal pairs that belong together, such as the chunks of a long message
+// a vector is shuffled as a unit: its pairs stay together and in order,
+// so the chunks of different ballots are never mixed with each other, and one chunk can't be swapped for another
+type CiphertextVector []*Ciphertext
+
+// VectorMixRecord is what a mix server publishes after shuffling a list of vectors, as MixRecord is for single pairs
+// every vector of the input and output has the same number of pairs
+type VectorMixRecord struct {
+	Suite     string             // the name of the cipher suite the ciphertexts belong to
+	Server    string             // the name of the mix server
+	PublicKey kyber.Point        // the public key the ciphertexts are encrypted with
+	Input     []CiphertextVector // the vectors the server was given
+	Output    []CiphertextVector // the re-encrypted and permuted vectors
+	Proof     []byte             // the proof of the shuffle
+}
+
+// EncryptLongMessageVector encrypts a long message as EncryptLongMessage does, with its chunks kept together in a vector
+func EncryptLongMessageVector(suite suites.Suite, data []byte, h kyber.Point) (messagePortions []kyber.Point, vector CiphertextVector, err error) {
+	messagePortions, elGamal1, elGamal2, err := EncryptLongMessage(suite, data, h)
+	if err != nil {
+		return nil, nil, err
+	}
+	if vector, err = JoinCiphertexts(elGamal1, elGamal2); err != nil {
+		return nil, nil, err
+	}
+	return // messagePortions, vector, nil
+}
+
+// GenerateLongMessageVectors generates n long messages as GenerateLongMessageEncryptions does, each encrypted as a vector
+// messages[i] holds the message portions of vectors[i], in order
+func GenerateLongMessageVectors(suite suites.Suite, n int, h kyber.Point) (messages [][]kyber.Point, vectors []CiphertextVector, err error) {
+	messages = make([][]kyber.Point, n)
+	vectors = make([]CiphertextVector, n)
+	for i := 0; i < n; i++ {
+		if messages[i], vectors[i], err = EncryptLongMessageVector(suite, sampleLongMessage(i), h); err != nil {
+			return nil, nil, fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	return // messages, vectors, nil
+}
+
+// ShuffleVectors re-encrypts and permutes a list of vectors encrypted with pubKey, moving the pairs of each vector together
+// uses kyber's sequence shuffle, whose proof reduces to a single pair shuffle over a random combination of the vectors
+// the combination is derived from the input and output, so the proof is non-interactive
+// returns the record of the shuffle, which isn't checked here, see VectorMixRecord.Verify
+// returns an error wrapping ErrInvalidShuffleProof for fewer than 2 vectors, which can't be shuffled
+func ShuffleVectors(suite suites.Suite, pubKey kyber.Point, input []CiphertextVector) (*VectorMixRecord, error) {
+	if pubKey == nil {
+		return nil, fmt.Errorf("%w: no public key", ErrInvalidShuffleProof)
+	}
+	if len(input) < minShuffleLength {
+		return nil, fmt.Errorf("%w: %d vectors can't be shuffled, at least %d are needed", ErrInvalidShuffleProof, len(input), minShuffleLength)
+	}
+	width, err := vectorWidth(input)
+	if err != nil {
+		return nil, err
+	}
+	elGamal1, elGamal2 := splitVectors(input, width)
+	shuffledElGamal1, shuffledElGamal2, getProver := shuffle.SequencesShuffle(suite, suite.Point().Base(), pubKey, elGamal1, elGamal2, suite.RandomStream())
+	record := &VectorMixRecord{
+		Suite:     suite.String(),
+		PublicKey: pubKey,
+		Input:     input,
+		Output:    joinVectors(shuffledElGamal1, shuffledElGamal2),
+	}
+
+	challenge, err := record.challenge(suite, width)
+	if err != nil {
+		return nil, err
+	}
+	prover, err := getProver(challenge)
+	if err != nil {
+		return nil, err
+	}
+	if record.Proof, err = proof.HashProve(suite, sequenceShuffleProtocol, prover); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ShuffleVectorsAndCheck shuffles a list of vectors, and verifies the shuffle, as ShuffleAndCheck does for pairs
+// returns an error wrapping ErrInvalidShuffleProof if the shuffle doesn't verify
+func ShuffleVectorsAndCheck(suite suites.Suite, h kyber.Point, input []CiphertextVector) (output []CiphertextVector, record *VectorMixRecord, err error) {
+	if record, err = ShuffleVectors(suite, h, input); err != nil {
+		return nil, nil, fmt.Errorf("shuffle proof failed: %w", err)
+	}
+	if err := record.Verify(suite); err != nil {
+		return nil, nil, err
+	}
+	return record.Output, record, nil
+}
+
+// Verify checks the proof of the shuffle in the record, which must belong to the given suite
+// only the record itself is needed, so this can be done by anyone, at any time
+func (r *VectorMixRecord) Verify(suite suites.Suite) error {
+	if err := checkSuite(suite, r.Suite, "vector mix record"); err != nil {
+		return err
+	}
+	if r.PublicKey == nil {
+		return fmt.Errorf("%w: mix record has no public key", ErrInvalidShuffleProof)
+	}
+	if len(r.Input) < minShuffleLength {
+		return fmt.Errorf("%w: %d vectors shuffled, a shuffle takes at least %d", ErrInvalidShuffleProof, len(r.Input), minShuffleLength)
+	}
+	width, err := vectorWidth(r.Input)
+	if err != nil {
+		return fmt.Errorf("%w: input: %v", ErrInvalidShuffleProof, err)
+	}
+	if outputWidth, err := vectorWidth(r.Output); err != nil || outputWidth != width || len(r.Output) != len(r.Input) {
+		return fmt.Errorf("%w: the output isn't %d vectors of %d pairs", ErrInvalidShuffleProof, len(r.Input), width)
+	}
+
+	challenge, err := r.challenge(suite, width)
+	if err != nil {
+		return err
+	}
+	elGamal1, elGamal2 := splitVectors(r.Input, width)
+	shuffledElGamal1, shuffledElGamal2 := splitVectors(r.Output, width)
+	XUp, YUp, XDown, YDown := shuffle.GetSequenceVerifiable(suite, elGamal1, elGamal2, shuffledElGamal1, shuffledElGamal2, challenge)
+	verifier := shuffle.Verifier(suite, suite.Point().Base(), r.PublicKey, XUp, YUp, XDown, YDown)
+	if err := proof.HashVerify(suite, sequenceShuffleProtocol, verifier, r.Proof); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidShuffleProof, err)
+	}
+	return nil
+}
+
+// helper function, the scalars the vectors are combined with for the proof, one per position in the vectors
+// they are derived from the public key, the input and the output, which the shuffler can't change once they are hashed
+func (r *VectorMixRecord) challenge(suite suites.Suite, width int) ([]kyber.Scalar, error) {
+	hash := sha256.New()
+	hash.Write([]byte(sequenceShuffleProtocol))
+	publicKey, err := r.PublicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	hash.Write(publicKey)
+	for _, vectors := range [][]CiphertextVector{r.Input, r.Output} {
+		for _, vector := range vectors {
+			for _, ciphertext := range vector {
+				data, err := ciphertext.MarshalBinary()
+				if err != nil {
+					return nil, err
+				}
+				hash.Write(data)
+			}
+		}
+	}
+	xof := suite.XOF(hash.Sum(nil))
+	challenge := make([]kyber.Scalar, width)
+	for j := range challenge {
+		challenge[j] = suite.Scalar().Pick(xof)
+	}
+	return challenge, nil
+}
+
+// helper function, the number of pairs of every vector of a list, which must all be the same
+func vectorWidth(vectors []CiphertextVector) (int, error) {
+	if len(vectors) == 0 {
+		return 0, errors.New("no vectors given")
+	}
+	width := len(vectors[0])
+	for i, vector := range vectors {
+		if len(vector) != width || width == 0 {
+			return 0, fmt.Errorf("vector %d holds %d pairs, vector 0 holds %d", i, len(vector), width)
+		}
+		for j, ciphertext := range vector {
+			if ciphertext == nil || ciphertext.C1 == nil || ciphertext.C2 == nil {
+				return 0, fmt.Errorf("vector %d: pair %d is missing", i, j)
+			}
+		}
+	}
+	return width, nil
+}
+
+// helper function, splits a list of vectors into the layout of kyber's sequence shuffle:
+// elGamal1[j][i] and elGamal2[j][i] are the halves of the j-th pair of the i-th vector
+func splitVectors(vectors []CiphertextVector, width int) (elGamal1, elGamal2 [][]kyber.Point) {
+	elGamal1 = make([][]kyber.Point, width)
+	elGamal2 = make([][]kyber.Point, width)
+	for j := 0; j < width; j++ {
+		elGamal1[j] = make([]kyber.Point, len(vectors))
+		elGamal2[j] = make([]kyber.Point, len(vectors))
+		for i, vector := range vectors {
+			elGamal1[j][i], elGamal2[j][i] = vector[j].C1, vector[j].C2
+		}
+	}
+	return // elGamal1, elGamal2
+}
+
+// helper function, the reverse of splitVectors
+func joinVectors(elGamal1, elGamal2 [][]kyber.Point) (vectors []CiphertextVector) {
+	if len(elGamal1) == 0 {
+		return nil
+	}
+	vectors = make([]CiphertextVector, len(elGamal1[0]))
+	for i := range vectors {
+		vectors[i] = make(CiphertextVector, len(elGamal1))
+		for j := range elGamal1 {
+			vectors[i][j] = &Ciphertext{C1: elGamal1[j][i], C2: elGamal2[j][i]}
+		}
+	}
+	return // vectors
+}
+
+// DecryptVectors decrypts a list of vectors with the shares of the trustees, as DecryptMessages does
+// returns the decrypted message portions of each vector, in order, and the indices of the trustees whose shadows failed verification
+func DecryptVectors(suite suites.Suite, vectors []CiphertextVector, shares []*vss.DistKeyShare, threshold, contributorCount int) (decrypted [][]kyber.Point, misbehaving []int, err error) {
+	flat := make([]*Ciphertext, 0, len(vectors))
+	for _, vector := range vectors {
+		flat = append(flat, vector...)
+	}
+	elGamal1, elGamal2, err := SplitCiphertexts(flat)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages, misbehaving, err := DecryptMessages(suite, elGamal1, elGamal2, shares, threshold, contributorCount)
+	if err != nil {
+		return nil, misbehaving, err
+	}
+	decrypted = make([][]kyber.Point, len(vectors))
+	for i, vector := range vectors {
+		decrypted[i], messages = messages[:len(vector)], messages[len(vector):]
+	}
+	return // decrypted, misbehaving, nil
+}
+
+// DecryptLongMessages decrypts a list of long messages, each encrypted as a vector, and puts each of them back together
+// the chunks of a message never left its vector, so no sorting or matching of prefixes is needed
+// returns the messages in the order of the vectors, and the indices of the trustees whose shadows failed verification
+func DecryptLongMessages(suite suites.Suite, vectors []CiphertextVector, shares []*vss.DistKeyShare, threshold, contributorCount int) (completeMessages []string, misbehaving []int, err error) {
+	decrypted, misbehaving, err := DecryptVectors(suite, vectors, shares, threshold, contributorCount)
+	if err != nil {
+		return nil, misbehaving, err
+	}
+	completeMessages = make([]string, len(decrypted))
+	for i, portions := range decrypted {
+		if completeMessages[i], err = CompileVector(portions); err != nil {
+			return nil, misbehaving, fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	return // completeMessages, misbehaving, nil
+}
+
+// the JSON form of a vector record, with the public key hex encoded
+type jsonVectorMixRecord struct {
+	Suite     string
+	Server    string
+	PublicKey string
+	Input     [][]*jsonCiphertext
+	Output    [][]*jsonCiphertext
+	Proof     []byte
+}
+
+// MarshalJSON encodes the record as a JSON object
+func (r *VectorMixRecord) MarshalJSON() ([]byte, error) {
+	publicKey, err := r.PublicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	encoded := jsonVectorMixRecord{
+		Suite:     r.Suite,
+		Server:    r.Server,
+		PublicKey: hex.EncodeToString(publicKey),
+		Input:     make([][]*jsonCiphertext, len(r.Input)),
+		Output:    make([][]*jsonCiphertext, len(r.Output)),
+		Proof:     r.Proof,
+	}
+	for i, vector := range r.Input {
+		if encoded.Input[i], err = encodeJSONCiphertexts(vector); err != nil {
+			return nil, err
+		}
+	}
+	for i, vector := range r.Output {
+		if encoded.Output[i], err = encodeJSONCiphertexts(vector); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a record encoded by MarshalJSON
+// the points are decoded with the suite the record names, which must be a supported suite
+func (r *VectorMixRecord) UnmarshalJSON(data []byte) error {
+	var encoded jsonVectorMixRecord
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	suite, err := FindSuite(encoded.Suite)
+	if err != nil {
+		return err
+	}
+	publicKey, err := hex.DecodeString(encoded.PublicKey)
+	if err != nil {
+		return err
+	}
+	if r.PublicKey, err = decodePoint(suite, publicKey); err != nil {
+		return err
+	}
+	r.Input = make([]CiphertextVector, len(encoded.Input))
+	for i, vector := range encoded.Input {
+		if r.Input[i], err = decodeJSONCiphertexts(suite, vector); err != nil {
+			return fmt.Errorf("input %d: %v", i, err)
+		}
+	}
+	r.Output = make([]CiphertextVector, len(encoded.Output))
+	for i, vector := range encoded.Output {
+		if r.Output[i], err = decodeJSONCiphertexts(suite, vector); err != nil {
+			return fmt.Errorf("output %d: %v", i, err)
+		}
+	}
+	r.Suite = encoded.Suite
+	r.Server = encoded.Server
+	r.Proof = encoded.Proof
+	return nil
+}
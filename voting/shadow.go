@@ -0,0 +1,88 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/proof/dleq"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// ErrInsufficientShadows is returned when fewer than threshold valid shadows are available for a decryption
+var ErrInsufficientShadows = errors.New("not enough valid shadows to decrypt")
+
+// ErrInvalidShadow is returned for a shadow whose proof doesn't hold against the dkg commitments
+var ErrInvalidShadow = errors.New("invalid shadow")
+
+// VerifiableShadow is a shadow (partial decryption) of an El Gamal message,
+// along with a Chaum-Pedersen proof that it was computed with the trustee's share
+// the proof shows that log_g(g^x_i) == log_(g^y)(g^(y*x_i)),
+// where g^x_i is the trustee's public verification key
+type VerifiableShadow struct {
+	Shadow *share.PubShare // the partial decryption g^(y*x_i), i
+	Proof  *dleq.Proof     // the discrete log equality proof
+}
+
+// VerificationKey derives the public verification key g^x_i of the trustee with the given index
+// from the public commitments of the dkg
+// anyone holding the commitments can do this, no secret is needed
+func VerificationKey(suite suites.Suite, commits []kyber.Point, index int) kyber.Point {
+	pubPoly := share.NewPubPoly(suite, nil, commits) // the public polynomial, with the base point as base
+	return pubPoly.Eval(index).V                     // g^x_i, the commitment to the trustee's share
+}
+
+// VerifyShadow checks the proof attached to a shadow
+// given the first half of the El Gamal message the shadow was extracted from,
+// and the public commitments of the dkg
+// returns an error if the shadow was not honestly computed
+func VerifyShadow(suite suites.Suite, elGamal1 kyber.Point, shadow *VerifiableShadow, commits []kyber.Point) error {
+	if shadow == nil || shadow.Shadow == nil || shadow.Proof == nil {
+		return fmt.Errorf("%w: shadow is missing its partial decryption or proof", ErrInvalidShadow)
+	}
+
+	verificationKey := VerificationKey(suite, commits, shadow.Shadow.I) // g^x_i
+
+	// check that the same exponent x_i was used for both g^x_i and g^(y*x_i)
+	err := shadow.Proof.Verify(suite, suite.Point().Base(), elGamal1, verificationKey, shadow.Shadow.V)
+	if err != nil {
+		return fmt.Errorf("%w: shadow of trustee %d failed verification: %v", ErrInvalidShadow, shadow.Shadow.I, err)
+	}
+	return nil
+}
+
+// VerifyShadows checks every shadow of an El Gamal message
+// given the number of trustees n, so shadows claiming an unknown index are caught
+// returns the shadows that passed, ready to be given to share.RecoverCommit,
+// and the indices of the trustees whose shadows failed verification
+// missing (nil) shadows belong to trustees that are offline, and are simply skipped,
+// as are copies of a shadow already used, but a second shadow that differs from it is faulty
+func VerifyShadows(suite suites.Suite, elGamal1 kyber.Point, shadows []*VerifiableShadow, commits []kyber.Point, n int) (valid []*share.PubShare, faulty []int) {
+	valid = make([]*share.PubShare, 0, len(shadows)) // allocate space for the verified shadows
+	seen := make(map[int]kyber.Point)                // the valid shadow of each trustee that already has one
+
+	for _, shadow := range shadows {
+		if shadow == nil || shadow.Shadow == nil {
+			continue // nothing was received from this trustee
+		}
+		index := shadow.Shadow.I
+		if index < 0 || index >= n {
+			faulty = append(faulty, index) // not a trustee of this election
+			continue
+		}
+		if used, ok := seen[index]; ok {
+			if shadow.Shadow.V == nil || !shadow.Shadow.V.Equal(used) {
+				faulty = append(faulty, index) // a valid shadow is unique, so the trustee sent a conflicting one
+			}
+			continue // otherwise a copy of the one already used
+		}
+		if err := VerifyShadow(suite, elGamal1, shadow, commits); err != nil {
+			faulty = append(faulty, index) // the trustee lied about its shadow
+			continue
+		}
+		seen[index] = shadow.Shadow.V
+		valid = append(valid, shadow.Shadow)
+	}
+	return // valid, faulty
+}
@@ -0,0 +1,165 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/proof"
+	"go.dedis.ch/kyber/v3/shuffle"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the name of the shuffle proof protocol, hashed into the proof
+const shuffleProtocol = "PairShuffle"
+
+// the fewest pairs a shuffle can permute, kyber panics on fewer
+const minShuffleLength = 2
+
+// ErrInvalidShuffleProof is returned when the proof of a shuffle doesn't hold
+var ErrInvalidShuffleProof = errors.New("invalid shuffle proof")
+
+// ShuffleAndCheck shuffles and verifies the shuffle of elGamal encrypted points
+// takes in the suite, the public key and two list which together represent a list of el Gamal pairs
+// along with the shuffled lists, returns the record of the shuffle, which can be published so anyone can audit it
+// returns an error wrapping ErrInvalidShuffleProof if the shuffle doesn't verify
+func ShuffleAndCheck(suite suites.Suite, h kyber.Point, elGamal1, elGamal2 []kyber.Point) (shuffledElGamal1, shuffledElGamal2 []kyber.Point, record *MixRecord, err error) {
+	if len(elGamal1) != len(elGamal2) {
+		return nil, nil, nil, fmt.Errorf("el gamal lists have different lengths (%d and %d)", len(elGamal1), len(elGamal2))
+	}
+
+	input, err := JoinCiphertexts(elGamal1, elGamal2)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	record, err = ShuffleCiphertexts(suite, h, input)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("shuffle proof failed: %w", err)
+	}
+
+	// Verify the proof
+	// each user could do this to the proof provided of the shuffle
+	// This will catch cheating done by the shuffler
+	if err := record.Verify(suite); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if shuffledElGamal1, shuffledElGamal2, err = SplitCiphertexts(record.Output); err != nil {
+		return nil, nil, nil, err
+	}
+	return // shuffledElGamal1, shuffledElGamal2, record, nil
+}
+
+// ShuffleCiphertexts re-encrypts and permutes a list of ciphertexts encrypted with pubKey
+// returns the record of the shuffle: the input and output lists, along with the proof
+// the proof is not checked here, the record is meant to be published and checked by anyone with MixRecord.Verify
+func ShuffleCiphertexts(suite suites.Suite, pubKey kyber.Point, input []*Ciphertext) (*MixRecord, error) {
+	elGamal1, elGamal2, err := SplitCiphertexts(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShuffleProof, err)
+	}
+	shuffledElGamal1, shuffledElGamal2, prf, err := ShuffleAndProve(suite, pubKey, elGamal1, elGamal2)
+	if err != nil {
+		return nil, err
+	}
+	output, err := JoinCiphertexts(shuffledElGamal1, shuffledElGamal2)
+	if err != nil {
+		return nil, err
+	}
+	return &MixRecord{
+		Suite:     suite.String(),
+		PublicKey: pubKey,
+		Input:     input,
+		Output:    output,
+		Proof:     prf,
+	}, nil
+}
+
+// ShuffleAndProve re-encrypts and permutes a list of el Gamal pairs,
+// and proves that the shuffle was performed correctly
+// the proof is not checked here: it is meant to be published, so anyone can check it with VerifyShuffle
+// returns an error wrapping ErrInvalidShuffleProof for fewer than 2 pairs, which can't be shuffled
+func ShuffleAndProve(suite suites.Suite, h kyber.Point, elGamal1, elGamal2 []kyber.Point) (shuffledElGamal1, shuffledElGamal2 []kyber.Point, prf []byte, err error) {
+	if len(elGamal1) != len(elGamal2) {
+		return nil, nil, nil, fmt.Errorf("el gamal lists have different lengths (%d and %d)", len(elGamal1), len(elGamal2))
+	}
+	if err := checkShuffleInput(h, elGamal1, elGamal2); err != nil {
+		return nil, nil, nil, err
+	}
+
+	shuffledElGamal1, shuffledElGamal2, prover := shuffle.Shuffle(suite, suite.Point().Base(), h, elGamal1[:], elGamal2[:], suite.RandomStream())
+
+	// Prove the shuffle
+	// This certifies that the shuffle was performed correctly,
+	// and prevents cheating
+	prf, err = proof.HashProve(suite, shuffleProtocol, prover)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return // shuffledElGamal1, shuffledElGamal2, prf, nil
+}
+
+// VerifyShuffle checks the proof that a list of el Gamal pairs is a shuffle of another
+// given the public key the pairs are encrypted with, both lists, and the proof
+func VerifyShuffle(suite suites.Suite, h kyber.Point, elGamal1, elGamal2, shuffledElGamal1, shuffledElGamal2 []kyber.Point, prf []byte) error {
+	if len(shuffledElGamal1) != len(elGamal1) || len(shuffledElGamal2) != len(elGamal2) || len(elGamal1) != len(elGamal2) {
+		return fmt.Errorf("%w: lists have different lengths", ErrInvalidShuffleProof)
+	}
+	if err := checkShuffleInput(h, elGamal1, elGamal2, shuffledElGamal1, shuffledElGamal2); err != nil {
+		return err
+	}
+
+	verifier := shuffle.Verifier(suite, suite.Point().Base(), h, elGamal1[:], elGamal2[:], shuffledElGamal1, shuffledElGamal2)
+	if err := proof.HashVerify(suite, shuffleProtocol, verifier, prf); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidShuffleProof, err)
+	}
+	return nil
+}
+
+// helper function, checks that lists of the same length are long enough to shuffle, and hold no missing points
+func checkShuffleInput(h kyber.Point, lists ...[]kyber.Point) error {
+	if h == nil {
+		return fmt.Errorf("%w: no public key", ErrInvalidShuffleProof)
+	}
+	if len(lists[0]) < minShuffleLength {
+		return fmt.Errorf("%w: %d pairs can't be shuffled, at least %d are needed", ErrInvalidShuffleProof, len(lists[0]), minShuffleLength)
+	}
+	for _, list := range lists {
+		for i, point := range list {
+			if point == nil {
+				return fmt.Errorf("%w: point %d is missing", ErrInvalidShuffleProof, i)
+			}
+		}
+	}
+	return nil
+}
+
+// DecryptMessage decrypts an El Gamal message
+// uses the secret key in the decryption process
+// this would be infeasable in a distributed environment,
+// but is useful for testing
+func DecryptMessage(suite suites.Suite, elGamal1, elGamal2 kyber.Point, secret kyber.Scalar) (message kyber.Point, err error) {
+	if elGamal1 == nil || elGamal2 == nil {
+		return nil, errors.New("el gamal message is missing")
+	}
+
+	toReverseElGamal := suite.Point().Mul(secret, elGamal1) // (g^y)^x == g^(xy)
+	message = suite.Point().Sub(elGamal2, toReverseElGamal) // M == Mg^(xy) / g^(xy)
+
+	return // message, nil
+}
+
+// DecryptAll decrypts all El Gamal messages with the secret key
+func DecryptAll(suite suites.Suite, elGamal1, elGamal2 []kyber.Point, secret kyber.Scalar) (decrpyedMessages []kyber.Point, err error) {
+	if len(elGamal1) != len(elGamal2) {
+		return nil, fmt.Errorf("el gamal lists have different lengths (%d and %d)", len(elGamal1), len(elGamal2))
+	}
+	decrpyedMessages = make([]kyber.Point, len(elGamal1)) // allocate space for the decrypted el gamal messages
+	for i := range elGamal1 {
+		if decrpyedMessages[i], err = DecryptMessage(suite, elGamal1[i], elGamal2[i], secret); err != nil { // decrypt each messsage
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+	}
+	return // decryptedMessages, nil
+}
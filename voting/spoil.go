@@ -0,0 +1,162 @@
+package voting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// ErrInvalidSpoiledBallot is returned when the messages and randomness revealed by a spoiled ballot don't give back its ciphertexts
+var ErrInvalidSpoiledBallot = errors.New("spoiled ballot doesn't open to its ciphertexts")
+
+// ErrSpoiledBallot is returned when casting a ballot that was spoiled
+// its randomness is public, so anyone could read the vote out of it
+var ErrSpoiledBallot = errors.New("ballot was spoiled")
+
+// PendingBallot is a ballot the voter's device just encrypted, before the voter decides what to do with it
+// this is Benaloh's cast-or-audit challenge: the device commits to the ballot without knowing whether it will be cast or spoiled,
+// so a device that encrypts something else than the voter's choice gets caught whenever the voter audits
+// the voter either casts it, and the randomness is forgotten, or spoils it, and the randomness is revealed
+type PendingBallot struct {
+	Ballot *Ballot // the encrypted ballot, with its proofs
+
+	messages   []kyber.Point  // the plaintexts of the ciphertexts
+	randomness []kyber.Scalar // the randomness of each encryption
+}
+
+// EncryptPendingBallot encrypts the message portions of a ballot as EncryptBallot does, keeping the randomness until the voter decides
+func EncryptPendingBallot(suite suites.Suite, messages []kyber.Point, pubKey kyber.Point, electionID, voterID string) *PendingBallot {
+	pending := &PendingBallot{
+		Ballot: &Ballot{
+			Suite:       suite.String(),
+			ElectionID:  electionID,
+			VoterID:     voterID,
+			Ciphertexts: make([]*Ciphertext, len(messages)),
+			Proofs:      make([]*EncryptionProof, len(messages)),
+		},
+		messages:   messages,
+		randomness: make([]kyber.Scalar, len(messages)),
+	}
+	for i, message := range messages {
+		elGamal1, elGamal2, randomness := EncryptMessageWithRandomness(suite, message, pubKey)
+		pending.Ballot.Ciphertexts[i] = &Ciphertext{C1: elGamal1, C2: elGamal2}
+		pending.Ballot.Proofs[i] = ProveEncryption(suite, pending.Ballot.Ciphertexts[i], randomness, electionID, voterID)
+		pending.randomness[i] = randomness
+	}
+	return pending
+}
+
+// Cast forgets the randomness, and returns the ballot to cast
+func (p *PendingBallot) Cast() *Ballot {
+	p.messages = nil
+	p.randomness = nil
+	return p.Ballot
+}
+
+// Spoil reveals the plaintexts and randomness of the ballot, so anyone can check its encryption
+// the spoiled ballot must never be cast: it is recorded as spoiled and left out of the tally
+// returns nil if the ballot was already cast
+func (p *PendingBallot) Spoil() *SpoiledBallot {
+	if p.randomness == nil {
+		return nil
+	}
+	return &SpoiledBallot{Ballot: p.Ballot, Messages: p.messages, Randomness: p.randomness}
+}
+
+// SpoiledBallot is a ballot that was audited instead of cast, along with everything needed to open it
+type SpoiledBallot struct {
+	Ballot     *Ballot        // the encrypted ballot
+	Messages   []kyber.Point  // the plaintexts of the ciphertexts, in order
+	Randomness []kyber.Scalar // the randomness of each encryption, in order
+}
+
+// Verify recomputes every ciphertext of the ballot from the revealed plaintext and randomness
+// this is what any verifier does with a spoiled ballot, no secret is needed
+func (s *SpoiledBallot) Verify(suite suites.Suite, pubKey kyber.Point) error {
+	if s == nil || s.Ballot == nil {
+		return fmt.Errorf("%w: the ballot is missing", ErrInvalidSpoiledBallot)
+	}
+	if err := checkSuite(suite, s.Ballot.Suite, "spoiled ballot"); err != nil {
+		return err
+	}
+	if len(s.Messages) != len(s.Ballot.Ciphertexts) || len(s.Randomness) != len(s.Ballot.Ciphertexts) {
+		return fmt.Errorf("%w: %d messages and %d randomness for %d ciphertexts", ErrInvalidSpoiledBallot, len(s.Messages), len(s.Randomness), len(s.Ballot.Ciphertexts))
+	}
+	for i, ciphertext := range s.Ballot.Ciphertexts {
+		if ciphertext == nil || s.Randomness[i] == nil || s.Messages[i] == nil {
+			return fmt.Errorf("%w: ciphertext %d is missing its opening", ErrInvalidSpoiledBallot, i)
+		}
+		elGamal1 := suite.Point().Mul(s.Randomness[i], nil)
+		elGamal2 := suite.Point().Mul(s.Randomness[i], pubKey)
+		elGamal2.Add(elGamal2, s.Messages[i])
+		if !ciphertext.Equal(&Ciphertext{C1: elGamal1, C2: elGamal2}) {
+			return fmt.Errorf("%w: ciphertext %d", ErrInvalidSpoiledBallot, i)
+		}
+	}
+	return nil
+}
+
+// the JSON form of a spoiled ballot, with every revealed point and scalar hex encoded
+type jsonSpoiledBallot struct {
+	Ballot     *Ballot
+	Messages   []string
+	Randomness []string
+}
+
+// MarshalJSON encodes the spoiled ballot as a JSON object
+func (s *SpoiledBallot) MarshalJSON() ([]byte, error) {
+	messages, err := encodePoints(s.Messages)
+	if err != nil {
+		return nil, err
+	}
+	randomness, err := encodeScalars(s.Randomness)
+	if err != nil {
+		return nil, err
+	}
+	encoded := jsonSpoiledBallot{Ballot: s.Ballot, Messages: make([]string, len(messages)), Randomness: make([]string, len(randomness))}
+	for i := range messages {
+		encoded.Messages[i] = hex.EncodeToString(messages[i])
+	}
+	for i := range randomness {
+		encoded.Randomness[i] = hex.EncodeToString(randomness[i])
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a spoiled ballot encoded by MarshalJSON
+// the revealed points and scalars are decoded with the suite the ballot names
+func (s *SpoiledBallot) UnmarshalJSON(data []byte) error {
+	var encoded jsonSpoiledBallot
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	if encoded.Ballot == nil {
+		return errors.New("spoiled ballot holds no ballot")
+	}
+	suite, err := FindSuite(encoded.Ballot.Suite)
+	if err != nil {
+		return err
+	}
+	raw := make([][]byte, len(encoded.Messages))
+	for i, message := range encoded.Messages {
+		if raw[i], err = hex.DecodeString(message); err != nil {
+			return fmt.Errorf("message %d: %v", i, err)
+		}
+	}
+	messages, err := decodePoints(suite, raw)
+	if err != nil {
+		return err
+	}
+	randomness, err := decodeHexScalars(suite, encoded.Randomness)
+	if err != nil {
+		return err
+	}
+	s.Ballot = encoded.Ballot
+	s.Messages = messages
+	s.Randomness = randomness
+	return nil
+}
@@ -0,0 +1,244 @@
+package voting
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// parameters of the on-disk format of a share
+const (
+	shareFileVersion = 1               // the current version of the format
+	shareKDF         = "pbkdf2-sha256" // the key derivation function used on the password
+	shareIterations  = 600000          // the number of iterations of the key derivation function
+	shareSaltLength  = 16              // the length of the salt, in bytes
+
+	// the iteration counts a stored share may ask for: fewer makes the password easy to guess,
+	// more lets a crafted file keep the loader busy for minutes
+	minShareIterations = 100000
+	maxShareIterations = 10000000
+)
+
+// ErrWrongPassword is returned when a stored share can't be decrypted,
+// either because the password is wrong or because the file was tampered with
+var ErrWrongPassword = errors.New("wrong password, or the stored share was tampered with")
+
+// StoredShare is the on-disk format of a trustee's share of the election key
+// the public parts are kept in the clear, so they can be inspected without the password,
+// while the private share is encrypted with AES-GCM under a key derived from the password
+// the public parts are authenticated along with the private share, so they can't be swapped out
+type StoredShare struct {
+	Version     int      // the version of the format
+	Suite       string   // the name of the cipher suite the share belongs to
+	Index       int      // the index of the trustee
+	PublicKey   []byte   // the public key of the election
+	Commitments [][]byte // the public commitments of the dkg
+
+	KDF        string // the key derivation function used on the password
+	Salt       []byte // the salt of the key derivation function
+	Iterations int    // the number of iterations of the key derivation function
+	Nonce      []byte // the nonce of the encryption
+	Ciphertext []byte // the encrypted private parts of the share
+}
+
+// the private parts of a share, as they are encrypted
+type storedSecret struct {
+	Share       []byte   // the private share x_i
+	PrivatePoly [][]byte // the coefficients of the trustee's own polynomial, needed to renew the share
+}
+
+// EncryptShare converts a share of the given suite to its on-disk format, encrypting its private parts with the password
+func EncryptShare(suite suites.Suite, distShare *vss.DistKeyShare, password []byte) (stored *StoredShare, err error) {
+	stored = &StoredShare{
+		Version:    shareFileVersion,
+		Suite:      suite.String(),
+		Index:      distShare.Share.I,
+		KDF:        shareKDF,
+		Iterations: shareIterations,
+	}
+	if stored.PublicKey, err = distShare.Public().MarshalBinary(); err != nil {
+		return nil, err
+	}
+	if stored.Commitments, err = encodePoints(distShare.Commits); err != nil {
+		return nil, err
+	}
+
+	// gather the private parts
+	secret := storedSecret{}
+	if secret.Share, err = distShare.Share.V.MarshalBinary(); err != nil {
+		return nil, err
+	}
+	if secret.PrivatePoly, err = encodeScalars(distShare.PrivatePoly); err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// encrypt them under a fresh salt and nonce
+	stored.Salt = make([]byte, shareSaltLength)
+	if _, err = rand.Read(stored.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := stored.cipher(password)
+	if err != nil {
+		return nil, err
+	}
+	stored.Nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(stored.Nonce); err != nil {
+		return nil, err
+	}
+	header, err := stored.header()
+	if err != nil {
+		return nil, err
+	}
+	stored.Ciphertext = aead.Seal(nil, stored.Nonce, plaintext, header)
+
+	return // stored, nil
+}
+
+// Decrypt recovers the share from its on-disk format, using the password it was encrypted with
+// the share must belong to the given suite
+func (stored *StoredShare) Decrypt(suite suites.Suite, password []byte) (*vss.DistKeyShare, error) {
+	if stored.Version != shareFileVersion {
+		return nil, fmt.Errorf("unsupported share format version %d", stored.Version)
+	}
+	if err := checkSuite(suite, stored.Suite, "share"); err != nil {
+		return nil, err
+	}
+
+	// decrypt the private parts, which also authenticates the public parts
+	aead, err := stored.cipher(password)
+	if err != nil {
+		return nil, err
+	}
+	header, err := stored.header()
+	if err != nil {
+		return nil, err
+	}
+	if len(stored.Nonce) != aead.NonceSize() { // Open panics on a nonce of the wrong size
+		return nil, fmt.Errorf("share nonce has %d bytes, expected %d", len(stored.Nonce), aead.NonceSize())
+	}
+	plaintext, err := aead.Open(nil, stored.Nonce, stored.Ciphertext, header)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	var secret storedSecret
+	if err := json.Unmarshal(plaintext, &secret); err != nil {
+		return nil, err
+	}
+
+	// rebuild the share
+	value, err := decodeScalar(suite, secret.Share)
+	if err != nil {
+		return nil, err
+	}
+	privatePoly, err := decodeScalars(suite, secret.PrivatePoly)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := stored.Commits(suite)
+	if err != nil {
+		return nil, err
+	}
+	return &vss.DistKeyShare{
+		Commits:     commits,
+		Share:       &share.PriShare{I: stored.Index, V: value},
+		PrivatePoly: privatePoly,
+	}, nil
+}
+
+// CipherSuite looks up the suite the share belongs to, as named in its public parts
+func (stored *StoredShare) CipherSuite() (suites.Suite, error) {
+	return FindSuite(stored.Suite)
+}
+
+// Public returns the public key of the election the share belongs to
+func (stored *StoredShare) Public(suite suites.Suite) (kyber.Point, error) {
+	if err := checkSuite(suite, stored.Suite, "share"); err != nil {
+		return nil, err
+	}
+	return decodePoint(suite, stored.PublicKey)
+}
+
+// Commits returns the public commitments of the dkg the share came from
+func (stored *StoredShare) Commits(suite suites.Suite) ([]kyber.Point, error) {
+	if err := checkSuite(suite, stored.Suite, "share"); err != nil {
+		return nil, err
+	}
+	return decodePoints(suite, stored.Commitments)
+}
+
+// derives the encryption key from the password, and sets up AES-GCM with it
+func (stored *StoredShare) cipher(password []byte) (cipher.AEAD, error) {
+	if stored.KDF != shareKDF {
+		return nil, fmt.Errorf("unsupported key derivation function %s", stored.KDF)
+	}
+	if stored.Iterations < minShareIterations || stored.Iterations > maxShareIterations {
+		return nil, fmt.Errorf("%d iterations of the key derivation function, not within %d to %d", stored.Iterations, minShareIterations, maxShareIterations)
+	}
+	key, err := pbkdf2.Key(sha256.New, string(password), stored.Salt, stored.Iterations, 32) // a key for AES-256
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// the public parts of the share, authenticated along with the private parts
+func (stored *StoredShare) header() ([]byte, error) {
+	public := *stored
+	public.Ciphertext = nil // everything but the ciphertext itself
+	return json.Marshal(public)
+}
+
+// SaveShare encrypts a share of the given suite with the password, and writes it to a file only the owner can read
+func SaveShare(suite suites.Suite, path string, distShare *vss.DistKeyShare, password []byte) error {
+	stored, err := EncryptShare(suite, distShare, password)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// ReadStoredShare reads a share from a file without decrypting it
+// only the public parts can be used until it is decrypted
+func ReadStoredShare(path string) (*StoredShare, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stored := &StoredShare{}
+	if err := json.Unmarshal(data, stored); err != nil {
+		return nil, fmt.Errorf("%s is not a stored share: %v", path, err)
+	}
+	return stored, nil
+}
+
+// LoadShare reads a share of the given suite from a file, and decrypts it with the password
+func LoadShare(suite suites.Suite, path string, password []byte) (*vss.DistKeyShare, error) {
+	stored, err := ReadStoredShare(path)
+	if err != nil {
+		return nil, err
+	}
+	return stored.Decrypt(suite, password)
+}
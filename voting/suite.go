@@ -0,0 +1,55 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// DefaultSuite is the name of the cipher suite used by elections that don't pick one
+const DefaultSuite = "ed25519" // Use the edwards25519-curve
+
+// ErrUnsupportedSuite is returned for a cipher suite that kyber doesn't know, or that can't hold ballots
+var ErrUnsupportedSuite = errors.New("unsupported cipher suite")
+
+// ErrSuiteMismatch is returned when an artifact belongs to another cipher suite than the one it is used with
+var ErrSuiteMismatch = errors.New("cipher suite mismatch")
+
+// FindSuite looks up a kyber cipher suite by name (e.g. "ed25519" or "P256"), and makes sure elections can use it
+// ballots are embedded into points, so only suites whose points can hold data are accepted
+func FindSuite(name string) (suites.Suite, error) {
+	suite, err := suites.Find(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrUnsupportedSuite, name, err)
+	}
+	if !SupportsEmbedding(suite) {
+		return nil, fmt.Errorf("%w: %s can't embed ballots into its points", ErrUnsupportedSuite, name)
+	}
+	return suite, nil
+}
+
+// SupportsEmbedding tells whether the points of a suite can hold a long message chunk:
+// the random prefix, the positional byte, and at least one byte of data
+// some groups (e.g. pairing groups) don't implement embedding at all, and panic when asked to
+func SupportsEmbedding(suite suites.Suite) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	if suite.Point().EmbedLen() <= randomnessLength+1 {
+		return false
+	}
+	data := []byte("embedding")
+	recovered, err := suite.Point().Embed(data, suite.RandomStream()).Data()
+	return err == nil && string(recovered) == string(data)
+}
+
+// helper function, checks that an artifact tagged with the name of a suite belongs to the suite in use
+func checkSuite(suite suites.Suite, name, artifact string) error {
+	if name != suite.String() {
+		return fmt.Errorf("%w: %s belongs to suite %s, not %s", ErrSuiteMismatch, artifact, name, suite.String())
+	}
+	return nil
+}
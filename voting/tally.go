@@ -0,0 +1,336 @@
+package voting
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/sign/schnorr"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// the name of the result signature, signed along with the result
+const tallyResultDomain = "crypto-voting/tally-result"
+
+// ErrInvalidResult is returned for a result document that isn't signed by its authority, or doesn't add up
+var ErrInvalidResult = errors.New("invalid tally result")
+
+// Tally counts decrypted long ballots against a ballot schema, question by question
+// ballots that aren't valid selections under the schema are counted as invalid, and left out of the counts
+// a tally isn't safe for concurrent use
+type Tally struct {
+	suite  suites.Suite
+	schema *BallotSchema
+	result *TallyResult
+}
+
+// TallyResult is the result document of a tally, signed by the authority that ran it
+type TallyResult struct {
+	Suite      string           // the name of the cipher suite of the authority's key
+	ElectionID string           // the election the ballots were cast in
+	Schema     []byte           // the fingerprint of the ballot schema the ballots were checked against
+	Questions  []*QuestionTally // the counts of each question, in the order of the schema
+	Ballots    int              // the number of ballots given to the tally
+	Counted    int              // the ballots that were valid, and counted
+	Invalid    int              // the ballots that couldn't be read, or broke the rules of the schema
+	Spoiled    int              // the ballots spoiled by their voters (see PendingBallot.Spoil), audited instead of counted
+	Authority  kyber.Point      // the public key of the authority that signed the result
+	Signature  []byte           // the authority's signature over everything else
+}
+
+// QuestionTally is the count of a question of the schema
+type QuestionTally struct {
+	ID       string         // the ID of the question
+	Counts   []int          // the number of votes of each candidate, in order
+	WriteIns map[string]int // the number of votes of each name written in
+	Blank    int            // the counted ballots that left the question blank
+}
+
+// NewTally creates an empty tally of the ballots of a schema
+func NewTally(suite suites.Suite, schema *BallotSchema) (*Tally, error) {
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	result := &TallyResult{Suite: suite.String(), ElectionID: schema.ElectionID, Schema: schema.fingerprint(), Questions: make([]*QuestionTally, len(schema.Questions))}
+	for i, question := range schema.Questions {
+		result.Questions[i] = &QuestionTally{ID: question.ID, Counts: make([]int, len(question.Candidates)), WriteIns: make(map[string]int)}
+	}
+	return &Tally{suite: suite, schema: schema, result: result}, nil
+}
+
+// Add decodes a decrypted ballot, and counts it if it is valid selections under the schema
+// returns an error wrapping ErrMalformedBallot or ErrInvalidSelection for an invalid ballot, which is counted as such
+func (t *Tally) Add(plaintext []byte) error {
+	t.result.Ballots++
+	selections, err := t.schema.DecodeSelections(plaintext)
+	if err != nil {
+		t.result.Invalid++
+		return err
+	}
+	t.count(selections)
+	return nil
+}
+
+// AddMessages counts the long messages put back together by CompileMessages or DecryptLongMessages
+// returns the reason each invalid message wasn't counted, by index
+func (t *Tally) AddMessages(messages []string) (rejected map[int]error) {
+	rejected = make(map[int]error)
+	for i, message := range messages {
+		if err := t.Add([]byte(message)); err != nil {
+			rejected[i] = err
+		}
+	}
+	return // rejected
+}
+
+// AddVectors counts the vectors decrypted by DecryptVectors, each holding a ballot
+// a vector that doesn't compile is an invalid ballot
+// returns the reason each invalid ballot wasn't counted, by index
+func (t *Tally) AddVectors(decrypted [][]kyber.Point) (rejected map[int]error) {
+	rejected = make(map[int]error)
+	for i, portions := range decrypted {
+		message, err := CompileVector(portions)
+		if err == nil {
+			err = t.Add([]byte(message))
+		} else {
+			t.result.Ballots++
+			t.result.Invalid++
+		}
+		if err != nil {
+			rejected[i] = err
+		}
+	}
+	return // rejected
+}
+
+// AddPortions puts the message portions decrypted by DecryptMessages back together under a reassembly policy, and counts them
+// ballots the policy doesn't keep are invalid ballots; portions that hold no chunk at all only show in the report
+// returns the reassembly report, and the reason each invalid ballot wasn't counted, by index in the report
+// if the policy fails the reassembly, nothing is counted
+func (t *Tally) AddPortions(decryptedMessages []kyber.Point, policy ReassemblyPolicy) (report *ReassemblyReport, rejected map[int]error, err error) {
+	report, err = ReassembleMessages(decryptedMessages, policy)
+	if err != nil {
+		return report, nil, err
+	}
+	rejected = make(map[int]error)
+	for i, ballot := range report.Ballots {
+		if !ballot.Kept {
+			t.result.Ballots++
+			t.result.Invalid++
+			rejected[i] = ballot.Err
+		} else if err := t.Add([]byte(ballot.Message)); err != nil {
+			rejected[i] = err
+		}
+	}
+	return // report, rejected, nil
+}
+
+// AddSpoiled records ballots spoiled by their voters, which are never decrypted with the others
+func (t *Tally) AddSpoiled(count int) {
+	t.result.Spoiled += count
+}
+
+// helper function, adds valid selections to the counts
+func (t *Tally) count(selections []*Selection) {
+	t.result.Counted++
+	for i, selection := range selections {
+		question := t.result.Questions[i]
+		if len(selection.Candidates) == 0 && len(selection.WriteIns) == 0 {
+			question.Blank++
+		}
+		for _, candidate := range selection.Candidates {
+			question.Counts[candidate]++
+		}
+		for _, name := range selection.WriteIns {
+			question.WriteIns[name]++
+		}
+	}
+}
+
+// Result signs the tally as it stands with the authority's private key, and returns the result document
+// the document is a copy: ballots added to the tally afterwards don't change it
+func (t *Tally) Result(private kyber.Scalar) (*TallyResult, error) {
+	result := *t.result
+	result.Questions = make([]*QuestionTally, len(t.result.Questions))
+	for i, question := range t.result.Questions {
+		copied := &QuestionTally{ID: question.ID, Counts: append([]int(nil), question.Counts...), WriteIns: make(map[string]int, len(question.WriteIns)), Blank: question.Blank}
+		for name, votes := range question.WriteIns {
+			copied.WriteIns[name] = votes
+		}
+		result.Questions[i] = copied
+	}
+	result.Authority = t.suite.Point().Mul(private, nil)
+
+	message, err := result.signedMessage()
+	if err != nil {
+		return nil, err
+	}
+	if result.Signature, err = schnorr.Sign(t.suite, private, message); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Verify checks that the result is signed by its authority, and that its counts add up under the schema
+// whoever relies on the result must also check that the authority is the one they expect
+func (r *TallyResult) Verify(suite suites.Suite, schema *BallotSchema) error {
+	if err := checkSuite(suite, r.Suite, "tally result"); err != nil {
+		return err
+	}
+	if r.Authority == nil || len(r.Signature) == 0 {
+		return fmt.Errorf("%w: result isn't signed", ErrInvalidResult)
+	}
+	message, err := r.signedMessage()
+	if err != nil {
+		return err
+	}
+	if err := schnorr.Verify(suite, r.Authority, message, r.Signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResult, err)
+	}
+
+	if r.ElectionID != schema.ElectionID || !isDataEqual(r.Schema, schema.fingerprint()) {
+		return fmt.Errorf("%w: result isn't for this ballot schema", ErrInvalidResult)
+	}
+	if r.Counted < 0 || r.Invalid < 0 || r.Spoiled < 0 || r.Ballots != r.Counted+r.Invalid {
+		return fmt.Errorf("%w: %d ballots, %d counted and %d invalid", ErrInvalidResult, r.Ballots, r.Counted, r.Invalid)
+	}
+	if len(r.Questions) != len(schema.Questions) {
+		return fmt.Errorf("%w: %d questions counted, the schema has %d", ErrInvalidResult, len(r.Questions), len(schema.Questions))
+	}
+	for i, question := range schema.Questions {
+		counted := r.Questions[i]
+		if counted == nil || counted.ID != question.ID || len(counted.Counts) != len(question.Candidates) {
+			return fmt.Errorf("%w: question %d doesn't match question %q of the schema", ErrInvalidResult, i, question.ID)
+		}
+		// every counted ballot answers the question within its limits, or leaves it blank
+		votes := 0
+		for j, count := range counted.Counts {
+			if count < 0 {
+				return fmt.Errorf("%w: question %q gives %d votes to candidate %d", ErrInvalidResult, question.ID, count, j)
+			}
+			votes += count
+		}
+		for name, count := range counted.WriteIns {
+			if count < 0 {
+				return fmt.Errorf("%w: question %q gives %d votes to write-in %q", ErrInvalidResult, question.ID, count, name)
+			}
+			votes += count
+		}
+		answered := r.Counted - counted.Blank
+		if counted.Blank < 0 || answered < 0 || votes < answered || votes > answered*question.MaxSelections {
+			return fmt.Errorf("%w: question %q has %d votes from %d answers", ErrInvalidResult, question.ID, votes, answered)
+		}
+	}
+	return nil
+}
+
+// helper function, what the authority signs: the JSON form of the result without its signature
+// JSON encodes struct fields in order, and map keys sorted, so the encoding is canonical
+func (r *TallyResult) signedMessage() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = nil
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(tallyResultDomain), data...), nil
+}
+
+// the JSON form of a result, with the authority's key hex encoded
+type jsonTallyResult struct {
+	Suite      string
+	ElectionID string
+	Schema     []byte
+	Questions  []*QuestionTally
+	Ballots    int
+	Counted    int
+	Invalid    int
+	Spoiled    int
+	Authority  string
+	Signature  []byte
+}
+
+// MarshalJSON encodes the result as a JSON object
+func (r *TallyResult) MarshalJSON() ([]byte, error) {
+	authority := ""
+	if r.Authority != nil {
+		data, err := r.Authority.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		authority = hex.EncodeToString(data)
+	}
+	return json.Marshal(jsonTallyResult{
+		Suite:      r.Suite,
+		ElectionID: r.ElectionID,
+		Schema:     r.Schema,
+		Questions:  r.Questions,
+		Ballots:    r.Ballots,
+		Counted:    r.Counted,
+		Invalid:    r.Invalid,
+		Spoiled:    r.Spoiled,
+		Authority:  authority,
+		Signature:  r.Signature,
+	})
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON, with the suite it names
+func (r *TallyResult) UnmarshalJSON(data []byte) error {
+	var encoded jsonTallyResult
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	suite, err := FindSuite(encoded.Suite)
+	if err != nil {
+		return err
+	}
+	var authority kyber.Point
+	if encoded.Authority != "" {
+		raw, err := hex.DecodeString(encoded.Authority)
+		if err != nil {
+			return fmt.Errorf("authority: %v", err)
+		}
+		if authority, err = decodePoint(suite, raw); err != nil {
+			return fmt.Errorf("authority: %v", err)
+		}
+	}
+	*r = TallyResult{
+		Suite:      encoded.Suite,
+		ElectionID: encoded.ElectionID,
+		Schema:     encoded.Schema,
+		Questions:  encoded.Questions,
+		Ballots:    encoded.Ballots,
+		Counted:    encoded.Counted,
+		Invalid:    encoded.Invalid,
+		Spoiled:    encoded.Spoiled,
+		Authority:  authority,
+		Signature:  encoded.Signature,
+	}
+	return nil
+}
+
+// SaveTallyResult writes a result document to a file, as JSON
+func SaveTallyResult(path string, result *TallyResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadTallyResult reads a result document written by SaveTallyResult
+// the result still has to be verified, see TallyResult.Verify
+func LoadTallyResult(path string) (*TallyResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result := &TallyResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("%s is not a tally result: %v", path, err)
+	}
+	return result, nil
+}
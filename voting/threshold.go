@@ -0,0 +1,406 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/proof/dleq"
+	"go.dedis.ch/kyber/v3/share"
+	vss "go.dedis.ch/kyber/v3/share/dkg/pedersen"
+	"go.dedis.ch/kyber/v3/suites"
+)
+
+// ErrDKGNotCertified is returned when the distributed key generation ends without every trustee being certified
+var ErrDKGNotCertified = errors.New("dkg is not certified")
+
+// ErrDecryptionMismatch is returned when decrypted messages don't match the messages that were encrypted
+var ErrDecryptionMismatch = errors.New("decrypted messages don't match")
+
+// from dedis github
+// generates a public/private key pair randomly
+func genPair(suite suites.Suite) (kyber.Scalar, kyber.Point) {
+	sc := suite.Scalar().Pick(suite.RandomStream())
+	return sc, suite.Point().Mul(sc, nil)
+}
+
+// DecryptMessages decrypts a list of messages
+// takes in the cipher suite of the election, the encrypted messages, the shares of the private threshold key,
+// and the parameters of the threshold cryptosystem
+// only the shares of the trustees that are online need to be given (offline trustees may also be left nil),
+// as long as there are at least threshold of them
+// returns the list of decrypted messages, and the indices of the trustees whose shadows failed verification
+// see DecryptMessagesParallel to spread the work over several cores
+func DecryptMessages(suite suites.Suite, elGamal1, elGamal2 []kyber.Point, shares []*vss.DistKeyShare, threshold, contributorCount int) (decryptedMessages []kyber.Point, misbehaving []int, err error) {
+
+	// only the shares of the trustees that are online are used
+	online, err := onlineShares(shares, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(elGamal2) != len(elGamal1) {
+		return nil, nil, fmt.Errorf("%d and %d el gamal halves given", len(elGamal1), len(elGamal2))
+	}
+
+	// the commitments are public, and the same for every share
+	// they are used to check the shadows of each contributor
+	commits := online[0].Commitments()
+
+	// allocate space for the partial decryptions of every message
+	shadows := make([][]*VerifiableShadow, len(elGamal1))
+
+	// extract the shadows of each of the messages
+	for i := range elGamal1 { // for each message
+
+		shadows[i] = make([]*VerifiableShadow, len(online)) // allocate space for the partial decryptions
+		for j := range online {
+			// each contributor could do this themselves
+			shadow, err := ExtractShadow(suite, elGamal1[i], elGamal2[i], online[j])
+			if err != nil {
+				return nil, nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			// the shadow extracted is a partial decryption of the given message
+			// each user has their own shadow for the message
+			shadows[i][j] = shadow
+		}
+	}
+
+	return DecryptMessagesFromShadows(suite, elGamal1, elGamal2, shadows, commits, threshold, contributorCount)
+}
+
+// DecryptMessagesFromShadows decrypts a list of messages from the shadows the trustees published for them
+// shadows[i] holds the shadows collected for message i, from whichever trustees responded
+// shadows that fail verification are left out of the decryption, and their trustees are reported
+// returns the list of decrypted messages, and the sorted indices of the trustees that misbehaved
+func DecryptMessagesFromShadows(suite suites.Suite, elGamal1, elGamal2 []kyber.Point, shadows [][]*VerifiableShadow, commits []kyber.Point, threshold, contributorCount int) (decryptedMessages []kyber.Point, misbehaving []int, err error) {
+	if len(elGamal2) != len(elGamal1) || len(shadows) != len(elGamal1) {
+		return nil, nil, fmt.Errorf("%d and %d el gamal halves given, with shadows for %d messages", len(elGamal1), len(elGamal2), len(shadows))
+	}
+
+	// allocate space for the decrypted el gamal messages
+	decryptedMessages = make([]kyber.Point, len(elGamal1))
+
+	faulty := make(map[int]bool) // the trustees caught misbehaving on any message
+
+	// decrypt each of the messages
+	for i := range elGamal1 { // for each message
+
+		// to decrypt the message, we take the encrypted message,
+		// the parameters of the threshold system,
+		// and the list of shadows from each of the users
+		message, faultyIndices, err := DecryptMessageSecretless(suite, elGamal1[i], elGamal2[i], shadows[i], commits, threshold, contributorCount)
+		for _, index := range faultyIndices {
+			faulty[index] = true
+		}
+		if err != nil {
+			return nil, sortedIndices(faulty), fmt.Errorf("message %d: %w", i, err)
+		}
+		decryptedMessages[i] = message
+	}
+
+	return decryptedMessages, sortedIndices(faulty), nil
+}
+
+// helper function, lists the keys of a set of indices in increasing order
+func sortedIndices(set map[int]bool) (indices []int) {
+	indices = make([]int, 0, len(set))
+	for index := range set {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	return // indices
+}
+
+// CreateThresholdShares does the preliminary step required for using a threshold cryptosystem
+// returns the shares that belong to the users
+// Each share has a private part, which is unique to that user,
+// and a public part, which is the public key for the threshold cryptosystem
+// (and is the same for all users)
+// returns ErrDKGNotCertified if any of the users ends up without a certified dkg
+func CreateThresholdShares(suite suites.Suite, contributorCount, threshold int) (shares []*vss.DistKeyShare, err error) {
+
+	// the users create their own dkgs using the public keys of the other users
+	// each dkg is all that is needed for the threshold system
+	dkgs, err := generate(suite, contributorCount, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// communicate between the dkgs
+	if err := fullShare(dkgs); err != nil {
+		return nil, err
+	}
+
+	// collect shares
+	// each user should have their own share
+	// creating a share fulfills the purpose of the dkg
+	shares = make([]*vss.DistKeyShare, 0, len(dkgs)) // allocate space for the shares
+	for i, shareholder := range dkgs {               // for each generator
+		if !shareholder.Certified() { // make sure it's certified
+			return nil, fmt.Errorf("%w: trustee %d", ErrDKGNotCertified, i)
+		}
+		newShare, err := shareholder.DistKeyShare() // get the share
+		if err != nil {
+			return nil, fmt.Errorf("trustee %d: %v", i, err)
+		}
+		shares = append(shares, newShare) // add it
+	}
+	return // shares, nil
+}
+
+// adapted from dedis github
+// makes public/private key pairs, and makes a dgk for each private key
+// publicly executable, as each dkg created only uses one private key
+// and the public keys
+// follows "A Threshold Cryptosystem Without a Trusted Party"
+func generate(suite suites.Suite, n, t int) (dkgs []*vss.DistKeyGenerator, err error) {
+	// Each public/private keypair represents the identity of a user
+	// in a distributed application, these keypairs will be created by each user independently
+	partPubs := make([]kyber.Point, n) // allocate space for public key parts
+	partSec := make([]kyber.Scalar, n) // allocate space for private key parts
+	for i := 0; i < n; i++ {           // for n users
+		sec, pub := genPair(suite)
+		partPubs[i] = pub // the public key for the user
+		partSec[i] = sec  // the private key for the user
+	}
+
+	// allocate space for the key generators
+	dkgs = make([]*vss.DistKeyGenerator, n)
+
+	// each user...
+	for i := 0; i < n; i++ {
+
+		// creates a key generator
+		// uses one user's private key
+		// the dkg created is now linked to that user
+		dkg, err := vss.NewDistKeyGenerator(suite, partSec[i], partPubs, t)
+		if err != nil {
+			return nil, fmt.Errorf("creating dkg of trustee %d: %v", i, err)
+		}
+		dkgs[i] = dkg
+	}
+	// after this point, the keypair is no longer used
+	// each dkg now effectively represents a user's identity
+
+	return // dkgs, nil
+}
+
+// EncryptMessage encrypts an El Gamal message
+func EncryptMessage(suite suites.Suite, message, pubKey kyber.Point) (elGamal1, elGamal2 kyber.Point) {
+	elGamal1, elGamal2, _ = EncryptMessageWithRandomness(suite, message, pubKey)
+	return
+}
+
+// EncryptMessageWithRandomness encrypts an El Gamal message,
+// and also returns the randomness y used in the encryption (g^y, Mg^(xy))
+// the randomness must be kept secret, it is only needed to prove things about the encryption
+func EncryptMessageWithRandomness(suite suites.Suite, message, pubKey kyber.Point) (elGamal1, elGamal2 kyber.Point, tempScalar kyber.Scalar) {
+	tempScalar = suite.Scalar().Pick(suite.RandomStream())
+	elGamal1 = suite.Point().Mul(tempScalar, nil)
+	elGamal2 = suite.Point().Mul(tempScalar, pubKey)
+	elGamal2.Add(elGamal2, message)
+
+	return
+}
+
+// DecryptMessageSecretless decrypts an El Gamal message
+// given the encrypted message, the list of shadows, the commitments of the dkg,
+// and the parameters for the threshold system
+// the decryption is "secretless" because the secret exponent is never revealed
+// every shadow is verified against the commitments before it is used,
+// and any threshold of valid shadows is enough to decrypt
+// returns the message, and the indices of the trustees whose shadows failed verification
+// follows the scheme outlined in Sections 2.2 and 3.1 of "Threshold Cryptosystems" by Desmedt and Frankel
+func DecryptMessageSecretless(suite suites.Suite, elGamal1, elGamal2 kyber.Point, shadows []*VerifiableShadow, commits []kyber.Point, t, n int) (message kyber.Point, faulty []int, err error) {
+
+	// leave out the shadows of the trustees that lied
+	pubShares, faulty := VerifyShadows(suite, elGamal1, shadows, commits, n)
+	if len(pubShares) < t {
+		return nil, faulty, fmt.Errorf("%w: %d valid shadows, threshold is %d", ErrInsufficientShadows, len(pubShares), t)
+	}
+
+	// With a message M and secret x, encrypted as the tuple (g^y, Mg^(xy))
+	// recovers the committment g^(xy)
+	// this acts as a key to decrypt the original message
+	// by dividing Mg^(xy) by g^(xy)
+
+	key, err := share.RecoverCommit(suite, pubShares, t, n) // recover g^(xy), essentially by multiplying its factors together
+	if err != nil {
+		return nil, faulty, err
+	}
+	message = suite.Point().Sub(elGamal2, key) // M = Mg^(xy) / g^(xy)
+
+	return // message, faulty, nil
+}
+
+// ExtractShadow follows the scheme outlined in Section 2.1 of "Threshold Cryptosystems" by Desmedt and Frankel
+// extracts the tuple g^kV_i, i
+// given a public el gamal encrpyed message,
+// and a share (kept private),
+// returns the corresponding shadow
+// this shadow essentially is a factor of the committment used
+// in the second half of an El Gamal encrypted message
+// the shadow comes with a Chaum-Pedersen proof, so anyone can check it against the dkg commitments
+func ExtractShadow(suite suites.Suite, elGamal1, elGamal2 kyber.Point, distShare *vss.DistKeyShare) (shadow *VerifiableShadow, err error) {
+
+	priv := distShare.PriShare() // private share x_i
+
+	// g^(y*x_i), along with a proof that log_g(g^x_i) == log_(g^y)(g^(y*x_i))
+	prf, _, value, err := dleq.NewDLEQProof(suite, suite.Point().Base(), elGamal1, priv.V)
+	if err != nil {
+		return nil, fmt.Errorf("proving shadow of trustee %d: %v", priv.I, err)
+	}
+
+	index := priv.I // record the index of the user to keep the shadws ordered
+	shadow = &VerifiableShadow{
+		Shadow: &share.PubShare{I: index, V: value}, // struct for recovering commit later
+		Proof:  prf,                                 // proof of correct extraction
+	}
+	return // shadow, nil
+}
+
+// communicates the required information for the key generators to function
+func fullShare(dkgs []*vss.DistKeyGenerator) error {
+
+	// This function shares all of the information between all dkgs
+	// the outline for the communication steps were provided on the dedis github,
+	// following an error-resistant implementation of the scheme described in
+	// "A Threshold Cryptosystem without a Trusted Party"
+
+	// There are three phases for the communication:
+	// 1) Deals
+	// Each user conveys information about itself to all others
+	// 2) Responses
+	// Each user makes sure that all of the deals it recieves are consistent
+	// If a given deal is compliant, the response indicate that it was accepted
+	// otherwise, the response will indicate that a justification for the deal is needed
+	// 3) Justifications
+	// This phase gives users the chance to justify their deal
+	// This can occur when either party has made a mistake
+	// Usually, no justification is needed, and none is given
+
+	// allocate space for the responses
+	resps := make([]*vss.Response, 0, len(dkgs)*len(dkgs))
+
+	// here, the dkgs know only the public keys of the other users
+	// more information is needed in order to create a share of the public threshold key
+
+	// deal all shares
+	for i, generator := range dkgs {
+		deals, err := generator.Deals() // each dkg has a deal for each other user
+		if err != nil {
+			return fmt.Errorf("deals of trustee %d: %v", i, err)
+		}
+
+		for j, deal := range deals { // for each deal
+			processor := dkgs[j]
+
+			//process the deal
+			response, err := processor.ProcessDeal(deal)
+			if err != nil {
+				return fmt.Errorf("trustee %d processing deal of trustee %d: %v", j, i, err)
+			}
+			// record the response to the deal
+			// index of response is contributorCount * sender index + reciever index
+			resps = append(resps, response)
+			// each response is
+
+		}
+	}
+	// all deals dealt
+
+	// distribute responses
+	for _, response := range resps {
+		for i, dkg := range dkgs { // everone can process every response
+
+			// don't justify to yourself
+			if uint32(i) == response.Response.Index {
+				continue
+			}
+
+			// handle response to the deal, justify deal to responder
+			justification, err := dkg.ProcessResponse(response)
+			if err != nil {
+				return fmt.Errorf("trustee %d processing response of trustee %d: %v", i, response.Response.Index, err)
+			}
+
+			// process justification
+			// This can be done directly after the response is given,
+			// independently of other responses
+
+			// justification will be nil if there is nothing to justify
+			// this is normally the case
+			if justification != nil {
+				sender := dkgs[response.Response.Index]
+				// make sure the justification is valid
+				if err := sender.ProcessJustification(justification); err != nil {
+					return fmt.Errorf("trustee %d processing justification of trustee %d: %v", response.Response.Index, justification.Index, err)
+				}
+			}
+		}
+	}
+	// all responses distributed
+	return nil
+}
+
+// SortablePointList is a wrapper for []]kyber.Point
+// this allows for these lists to be sorted
+type SortablePointList []kyber.Point
+
+// functions required for sorting
+func (a SortablePointList) Len() int           { return len(a) }
+func (a SortablePointList) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a SortablePointList) Less(i, j int) bool { return a[i].String() < a[j].String() }
+
+// CheckDecryption compares two lists of points
+// checks if the decryptions of messages matches the original messages
+// returns ErrDecryptionMismatch if they don't
+func CheckDecryption(messages, decryptedMessages []kyber.Point) error {
+
+	// message lengths match up
+	if len(messages) != len(decryptedMessages) {
+		return fmt.Errorf("%w: %d messages, %d decrypted", ErrDecryptionMismatch, len(messages), len(decryptedMessages))
+	}
+
+	// sort the lists, as one may have been shuffled
+	sort.Sort(SortablePointList(messages))
+	sort.Sort(SortablePointList(decryptedMessages))
+
+	// check each pair of messages
+	for i := range messages {
+		// fmt.Println("Message:", messages[i])
+		// fmt.Println("Decrypted Message:", decryptedMessages[i])
+		// fmt.Println()
+		if messages[i].Equal(decryptedMessages[i]) == false {
+			return fmt.Errorf("%w: message incorrectly decrypted", ErrDecryptionMismatch) // messages do not match
+		}
+	}
+	return nil
+}
+
+// GenerateMessageEncryptions generates a list of messages alongside their encryptions
+// generates n messages and their encryptions with a given public key h
+// encryptions are done in El Gamal,
+// where each index represents an encrypted message
+// in pseudocode: encrypt(message[i]) == (elGamal1[i], elGamal2[i])
+func GenerateMessageEncryptions(suite suites.Suite, n int, h kyber.Point) (messages, elGamal1, elGamal2 []kyber.Point) {
+
+	messages = make([]kyber.Point, n) // the el gamal messages
+	elGamal1 = make([]kyber.Point, n) // the el gamal pairs
+	elGamal2 = make([]kyber.Point, n) // the el gamal pairs
+
+	// initialize the elGamal pairs
+	// // pick random messages
+	// pick meaningful messages
+	// and encrypt them with the threshold public key
+	for i := range messages {
+		// messages[i] = suite.Point().Pick(suite.RandomStream())
+		data := []byte("Sample Message " + strconv.Itoa(i))
+		messages[i] = suite.Point().Embed(data, suite.RandomStream())
+		elGamal1[i], elGamal2[i] = EncryptMessage(suite, messages[i], h) // can use any share's public key
+	}
+
+	return // messages, elGamal1, elGamal2
+}
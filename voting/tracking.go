@@ -0,0 +1,207 @@
+package voting
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// the number of bytes of the ballot hash kept in a tracking code
+// 15 bytes give 24 characters, and make it infeasible to craft two ballots with the same code
+const trackingCodeLength = 15
+
+// ErrUnknownTrackingCode is returned when no cast ballot has the tracking code looked up
+var ErrUnknownTrackingCode = errors.New("no ballot with this tracking code")
+
+// ErrInvalidInclusionProof is returned when an inclusion proof doesn't lead to the root of the ballot tree
+var ErrInvalidInclusionProof = errors.New("invalid inclusion proof")
+
+// TrackingCode derives the short code a voter keeps to find their ballot once it is cast
+// the code is the start of the hash of the ballot's canonical encoding, in base32, in groups of four characters
+func (b *Ballot) TrackingCode() (string, error) {
+	hash, err := b.Hash()
+	if err != nil {
+		return "", err
+	}
+	return trackingCode(hash), nil
+}
+
+// helper function, the tracking code of a ballot hash
+func trackingCode(hash []byte) string {
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(hash[:trackingCodeLength])
+	groups := make([]string, 0, len(encoded)/4+1)
+	for len(encoded) > 4 {
+		groups = append(groups, encoded[:4])
+		encoded = encoded[4:]
+	}
+	return strings.Join(append(groups, encoded), "-")
+}
+
+// BallotTree is a Merkle tree over the hashes of every cast ballot, in the order they were cast
+// publishing its root commits to the whole list of ballots,
+// and an inclusion proof shows a voter their ballot is in the list without handing them the other ballots
+// the tree is built as in RFC 6962, with leaves and inner nodes hashed under different prefixes
+type BallotTree struct {
+	leaves [][]byte       // the hashes of the ballots, in order
+	codes  map[string]int // the index of the ballot with each tracking code
+}
+
+// InclusionProof shows that a ballot is the leaf at Index of a ballot tree of Size leaves
+type InclusionProof struct {
+	Index      int      // the position of the ballot in the tree
+	Size       int      // the number of ballots in the tree
+	BallotHash []byte   // the hash of the ballot
+	Path       [][]byte // the hashes of the sibling subtrees, from the leaf up to the root
+}
+
+// NewBallotTree builds the tree over a list of cast ballots
+// anyone can rebuild it from the ballots posted on the bulletin board, and must find the same root
+func NewBallotTree(ballots []*Ballot) (*BallotTree, error) {
+	tree := &BallotTree{leaves: make([][]byte, len(ballots)), codes: make(map[string]int, len(ballots))}
+	for i, ballot := range ballots {
+		hash, err := ballot.Hash()
+		if err != nil {
+			return nil, fmt.Errorf("ballot %d: %v", i, err)
+		}
+		tree.leaves[i] = hash
+		if _, ok := tree.codes[trackingCode(hash)]; !ok {
+			tree.codes[trackingCode(hash)] = i
+		}
+	}
+	return tree, nil
+}
+
+// Len returns the number of ballots in the tree
+func (t *BallotTree) Len() int { return len(t.leaves) }
+
+// Root returns the hash at the root of the tree
+func (t *BallotTree) Root() []byte {
+	return merkleRoot(t.leaves)
+}
+
+// Lookup finds the ballot with the given tracking code, and proves it is in the tree
+// the code is matched regardless of case and of the dashes between its groups
+func (t *BallotTree) Lookup(code string) (*InclusionProof, error) {
+	index, ok := t.codes[normalizeTrackingCode(code)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTrackingCode, code)
+	}
+	return &InclusionProof{
+		Index:      index,
+		Size:       len(t.leaves),
+		BallotHash: t.leaves[index],
+		Path:       merklePath(index, t.leaves),
+	}, nil
+}
+
+// helper function, puts a tracking code typed in by a voter back in its canonical form
+func normalizeTrackingCode(code string) string {
+	compact := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	groups := make([]string, 0, len(compact)/4+1)
+	for len(compact) > 4 {
+		groups = append(groups, compact[:4])
+		compact = compact[4:]
+	}
+	return strings.Join(append(groups, compact), "-")
+}
+
+// Verify checks that the proof leads from the ballot to the given root
+// a voter holding their tracking code checks the code against BallotHash, and the root against the one that was published
+func (p *InclusionProof) Verify(root []byte) error {
+	if p.Index < 0 || p.Index >= p.Size {
+		return fmt.Errorf("%w: index %d in a tree of %d ballots", ErrInvalidInclusionProof, p.Index, p.Size)
+	}
+	hash, rest, err := merkleClimb(p.Index, p.Size, merkleLeaf(p.BallotHash), p.Path)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("%w: %d hashes too many", ErrInvalidInclusionProof, len(rest))
+	}
+	if !bytes.Equal(hash, root) {
+		return fmt.Errorf("%w: the path doesn't lead to the root", ErrInvalidInclusionProof)
+	}
+	return nil
+}
+
+// TrackingCode returns the tracking code of the ballot the proof is about
+func (p *InclusionProof) TrackingCode() string {
+	if len(p.BallotHash) < trackingCodeLength {
+		return ""
+	}
+	return trackingCode(p.BallotHash)
+}
+
+// helper function, the hash of a leaf
+func merkleLeaf(ballotHash []byte) []byte {
+	hash := sha256.Sum256(append([]byte{0}, ballotHash...))
+	return hash[:]
+}
+
+// helper function, the hash of an inner node
+func merkleNode(left, right []byte) []byte {
+	hash := sha256.Sum256(append(append([]byte{1}, left...), right...))
+	return hash[:]
+}
+
+// helper function, the largest power of two smaller than n, where the tree of n leaves is split
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// helper function, the root of the tree over the given ballot hashes
+// the root of an empty tree is the hash of nothing
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		hash := sha256.Sum256(nil)
+		return hash[:]
+	case 1:
+		return merkleLeaf(leaves[0])
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNode(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// helper function, the hashes of the siblings of a leaf, from the leaf up to the root
+func merklePath(index int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := merkleSplit(len(leaves))
+	if index < k {
+		return append(merklePath(index, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(index-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// helper function, climbs from the hash of a leaf to the root of a tree of size leaves, consuming the path
+// returns the root, and whatever is left of the path
+func merkleClimb(index, size int, hash []byte, path [][]byte) (root []byte, rest [][]byte, err error) {
+	if size <= 1 {
+		return hash, path, nil
+	}
+	k := merkleSplit(size)
+	if index < k {
+		hash, path, err = merkleClimb(index, k, hash, path)
+	} else {
+		hash, path, err = merkleClimb(index-k, size-k, hash, path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("%w: the path is too short", ErrInvalidInclusionProof)
+	}
+	if index < k {
+		return merkleNode(hash, path[0]), path[1:], nil
+	}
+	return merkleNode(path[0], hash), path[1:], nil
+}